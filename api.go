@@ -0,0 +1,601 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/FayKn/RDW-Kenteken-Api/kenteken"
+)
+
+const (
+	defaultAPIAddr = ":8000"
+
+	defaultBatchMaxKentekens = 5000
+	defaultBatchChunkSize    = 500
+)
+
+// apiError is the body returned for every non successful API response.
+type apiError struct {
+	Error string `json:"error"`
+}
+
+// runServer starts the HTTP API on API_ADDR (default :8000).
+func runServer() {
+	store, err := openStore()
+	if err != nil {
+		log.Fatal("Error connecting to the database ", err)
+	}
+
+	addr := getEnvVar("API_ADDR")
+	if addr == "" {
+		addr = defaultAPIAddr
+	}
+
+	log.Printf("API listening on %s", addr)
+	log.Fatal(http.ListenAndServe(addr, newRouter(store)))
+}
+
+// newRouter serves the vehicles and the tables around them from store.
+func newRouter(store VehicleStore) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/voertuigen", handleSearchVoertuigen(store))
+	mux.HandleFunc("GET /v1/voertuigen/{kenteken}", handleGetVoertuig(store))
+	mux.HandleFunc("POST /v1/voertuigen:batch", handleBatchVoertuigen(store))
+	mux.HandleFunc("GET /v1/voertuigen/{kenteken}/history", handleGetVoertuigHistory(store))
+	mux.HandleFunc("GET /v1/voertuigen/{kenteken}/terugroepacties", handleGetVoertuigRecalls(store))
+	mux.HandleFunc("GET /v1/voertuigen/{kenteken}/keuringen", handleGetVoertuigKeuringen(store))
+	mux.HandleFunc("GET /v1/meta/stats", handleGetStats(store))
+	mux.HandleFunc("GET /v1/meta/imports", handleGetImports(store))
+	mux.HandleFunc("GET /resource/"+voertuigenResource+".json", handleSodaVoertuigen(store))
+	return mux
+}
+
+// voertuigResponse is the body of GET /v1/voertuigen/{kenteken}: the record
+// plus the rows of the linked datasets asked for with ?embed=.
+type voertuigResponse struct {
+	Record   projectedRecord
+	Embedded map[string][]map[string]any
+}
+
+// MarshalJSON puts embedded next to the fields of the record.
+func (v voertuigResponse) MarshalJSON() ([]byte, error) {
+	data, err := json.Marshal(v.Record)
+	if err != nil || len(v.Embedded) == 0 {
+		return data, err
+	}
+	embedded, err := json.Marshal(v.Embedded)
+	if err != nil {
+		return nil, err
+	}
+	data = append(data[:len(data)-1], `,"embedded":`...)
+	return append(append(data, embedded...), '}'), nil
+}
+
+// requestFormat negotiates the response format of a request among formats.
+// When none of the accepted formats can be served it writes a 406 and
+// returns false.
+func requestFormat(w http.ResponseWriter, r *http.Request, formats ...string) (string, bool) {
+	w.Header().Add("Vary", "Accept")
+	format, ok := negotiateFormat(r.Header.Get("Accept"), formats)
+	if !ok {
+		writeError(w, http.StatusNotAcceptable, "supported formats: "+strings.Join(formats, ", "))
+	}
+	return format, ok
+}
+
+// requestKenteken normalizes the {kenteken} of a request. Plates that match
+// no sidecode are looked up anyway: the importer stores every plate of the
+// RDW, including ones outside the sidecodes. An empty plate writes a 400 and
+// returns false.
+func requestKenteken(w http.ResponseWriter, r *http.Request) (string, bool) {
+	plate := kenteken.Normalize(r.PathValue("kenteken"))
+	if plate == "" {
+		writeError(w, http.StatusBadRequest, kenteken.ErrEmpty.Error())
+		return "", false
+	}
+	return plate, true
+}
+
+// writeKentekenNotFound answers a plate that is not in the database: a 400
+// with the reason when it is not a valid plate either, a 404 otherwise.
+func writeKentekenNotFound(w http.ResponseWriter, plate string) {
+	if _, err := kenteken.Validate(plate); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeError(w, http.StatusNotFound, "kenteken not found")
+}
+
+// handleGetVoertuig serves GET /v1/voertuigen/{kenteken}. fields= limits the
+// columns, the Accept header picks JSON, CSV or NDJSON.
+func handleGetVoertuig(store VehicleStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		format, ok := requestFormat(w, r, recordFormats...)
+		if !ok {
+			return
+		}
+		plate, ok := requestKenteken(w, r)
+		if !ok {
+			return
+		}
+		columns, err := parseProjection(r.URL.Query().Get("fields"))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		var embed []rdwDataset
+		if value := r.URL.Query().Get("embed"); value != "" {
+			for _, name := range strings.Split(value, ",") {
+				dataset, ok := findDataset(linkedDatasets, name)
+				if !ok {
+					writeError(w, http.StatusBadRequest, "unknown dataset in embed: "+name)
+					return
+				}
+				embed = append(embed, dataset)
+			}
+			if format != formatJSON {
+				writeError(w, http.StatusBadRequest, "embed is only supported for JSON")
+				return
+			}
+		}
+
+		record, err := store.Get(plate, columns)
+		if errors.Is(err, sql.ErrNoRows) {
+			writeKentekenNotFound(w, plate)
+			return
+		}
+		if err != nil {
+			log.Println("Error fetching voertuig ", err)
+			writeError(w, http.StatusInternalServerError, "database error")
+			return
+		}
+
+		if format != formatJSON {
+			writeRecords(w, format, columns, []RDWRecord{record})
+			return
+		}
+
+		response := voertuigResponse{Record: projectedRecord{record: record, columns: columns}}
+		for _, dataset := range embed {
+			rows, err := store.DatasetRows(dataset, "kenteken", plate)
+			if err != nil {
+				log.Println("Error fetching "+dataset.name+" ", err)
+				writeError(w, http.StatusInternalServerError, "database error")
+				return
+			}
+			if response.Embedded == nil {
+				response.Embedded = make(map[string][]map[string]any)
+			}
+			response.Embedded[dataset.name] = rows
+		}
+
+		writeJSON(w, http.StatusOK, response)
+	}
+}
+
+// searchResponse is the body of GET /v1/voertuigen. NextCursor is null on the
+// last page.
+type searchResponse struct {
+	Data       []projectedRecord `json:"data"`
+	NextCursor *string           `json:"next_cursor"`
+}
+
+// handleSearchVoertuigen serves GET /v1/voertuigen. At most SEARCH_MAX_LIMIT
+// (default 1000) vehicles are returned per page. CSV and NDJSON responses
+// have no envelope, the next page is in the Link header.
+func handleSearchVoertuigen(store VehicleStore) http.HandlerFunc {
+	maxLimit := defaultSearchMaxLimit
+	if getEnvVar("SEARCH_MAX_LIMIT") != "" {
+		maxLimit = getIntEnvVar("SEARCH_MAX_LIMIT")
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		format, ok := requestFormat(w, r, recordFormats...)
+		if !ok {
+			return
+		}
+		query, err := parseSearchQuery(r.URL.Query(), maxLimit)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		records, cursor, err := store.Search(query)
+		if err != nil {
+			log.Println("Error searching voertuigen ", err)
+			writeError(w, http.StatusInternalServerError, "database error")
+			return
+		}
+
+		response := searchResponse{Data: query.fields.records(records)}
+		if cursor != "" {
+			next := *r.URL
+			values := next.Query()
+			values.Set("cursor", cursor)
+			next.RawQuery = values.Encode()
+			w.Header().Set("Link", "<"+next.RequestURI()+`>; rel="next"`)
+			response.NextCursor = &cursor
+		}
+		if format != formatJSON {
+			writeRecords(w, format, query.fields, records)
+			return
+		}
+		writeJSON(w, http.StatusOK, response)
+	}
+}
+
+// batchRequest is the body of POST /v1/voertuigen:batch.
+type batchRequest struct {
+	Kentekens []string `json:"kentekens"`
+}
+
+// errTooManyKentekens is returned by decodeBatchRequest for a list longer
+// than the limit.
+var errTooManyKentekens = errors.New("too many kentekens")
+
+// decodeBatchRequest reads a batchRequest from r. The kentekens are read one
+// by one so that a list longer than maxKentekens is refused as soon as the
+// limit is passed instead of after decoding all of it.
+func decodeBatchRequest(r io.Reader, maxKentekens int) (batchRequest, error) {
+	var request batchRequest
+	decoder := json.NewDecoder(r)
+	if err := expectDelim(decoder, '{'); err != nil {
+		return request, err
+	}
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return request, err
+		}
+		if key, _ := token.(string); !strings.EqualFold(key, "kentekens") {
+			var ignored json.RawMessage
+			if err := decoder.Decode(&ignored); err != nil {
+				return request, err
+			}
+			continue
+		}
+
+		if err := expectDelim(decoder, '['); err != nil {
+			return request, err
+		}
+		request.Kentekens = []string{}
+		for decoder.More() {
+			if len(request.Kentekens) == maxKentekens {
+				return request, errTooManyKentekens
+			}
+			var plate string
+			if err := decoder.Decode(&plate); err != nil {
+				return request, err
+			}
+			request.Kentekens = append(request.Kentekens, plate)
+		}
+		if err := expectDelim(decoder, ']'); err != nil {
+			return request, err
+		}
+	}
+	return request, expectDelim(decoder, '}')
+}
+
+// expectDelim reads the next token of decoder and fails unless it is delim.
+func expectDelim(decoder *json.Decoder, delim json.Delim) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	if token != delim {
+		return fmt.Errorf("expected %v, got %v", delim, token)
+	}
+	return nil
+}
+
+// batchResponse lists the found vehicles, the valid plates that are not in
+// the database and the plates that are neither in the database nor valid.
+type batchResponse struct {
+	Found    []projectedRecord `json:"found"`
+	NotFound []string          `json:"not_found"`
+	Invalid  []invalidKenteken `json:"invalid"`
+}
+
+type invalidKenteken struct {
+	Kenteken string `json:"kenteken"`
+	Error    string `json:"error"`
+}
+
+// handleBatchVoertuigen serves POST /v1/voertuigen:batch. It accepts up to
+// BATCH_MAX_KENTEKENS (default 5000) plates and fetches them in chunks of
+// BATCH_CHUNK_SIZE (default 500) per query. fields= limits the columns of the
+// found vehicles. CSV and NDJSON responses only contain the found vehicles.
+func handleBatchVoertuigen(store VehicleStore) http.HandlerFunc {
+	maxKentekens := defaultBatchMaxKentekens
+	if getEnvVar("BATCH_MAX_KENTEKENS") != "" {
+		maxKentekens = getIntEnvVar("BATCH_MAX_KENTEKENS")
+	}
+	chunkSize := defaultBatchChunkSize
+	if getEnvVar("BATCH_CHUNK_SIZE") != "" {
+		chunkSize = getIntEnvVar("BATCH_CHUNK_SIZE")
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		format, ok := requestFormat(w, r, recordFormats...)
+		if !ok {
+			return
+		}
+		columns, err := parseProjection(r.URL.Query().Get("fields"))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		// a generous upper bound for maxKentekens quoted plates
+		r.Body = http.MaxBytesReader(w, r.Body, int64(maxKentekens)*64+1024)
+		request, err := decodeBatchRequest(r.Body, maxKentekens)
+		var tooLarge *http.MaxBytesError
+		if errors.Is(err, errTooManyKentekens) || errors.As(err, &tooLarge) {
+			writeError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("at most %d kentekens per request", maxKentekens))
+			return
+		}
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+			return
+		}
+
+		response := batchResponse{NotFound: []string{}, Invalid: []invalidKenteken{}}
+		var plates []string
+		var found []RDWRecord
+		seen := make(map[string]bool, len(request.Kentekens))
+		for _, input := range request.Kentekens {
+			plate := kenteken.Normalize(input)
+			if plate == "" {
+				response.Invalid = append(response.Invalid, invalidKenteken{Kenteken: input, Error: kenteken.ErrEmpty.Error()})
+				continue
+			}
+			if !seen[plate] {
+				seen[plate] = true
+				plates = append(plates, plate)
+			}
+		}
+
+		for start := 0; start < len(plates); start += chunkSize {
+			chunk := plates[start:min(start+chunkSize, len(plates))]
+			records, err := store.GetMany(chunk, columns)
+			if err != nil {
+				log.Println("Error fetching voertuigen ", err)
+				writeError(w, http.StatusInternalServerError, "database error")
+				return
+			}
+			for _, plate := range chunk {
+				if record, ok := records[plate]; ok {
+					found = append(found, record)
+				} else if _, err := kenteken.Validate(plate); err != nil {
+					response.Invalid = append(response.Invalid, invalidKenteken{Kenteken: plate, Error: err.Error()})
+				} else {
+					response.NotFound = append(response.NotFound, plate)
+				}
+			}
+		}
+
+		if format != formatJSON {
+			writeRecords(w, format, columns, found)
+			return
+		}
+		response.Found = columns.records(found)
+		writeJSON(w, http.StatusOK, response)
+	}
+}
+
+// voertuigHistory is the body of GET /v1/voertuigen/{kenteken}/history.
+type voertuigHistory struct {
+	Kenteken string           `json:"kenteken"`
+	Versions []historyVersion `json:"versions"`
+}
+
+// handleGetVoertuigHistory serves GET /v1/voertuigen/{kenteken}/history.
+func handleGetVoertuigHistory(store VehicleStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := requestFormat(w, r, formatJSON); !ok {
+			return
+		}
+		plate, ok := requestKenteken(w, r)
+		if !ok {
+			return
+		}
+
+		versions, err := store.History(plate)
+		if err == nil && len(versions) == 0 {
+			// no history yet, tell unknown plates apart from unchanged ones
+			_, err = store.Get(plate, kentekenProjection)
+		}
+		if errors.Is(err, sql.ErrNoRows) {
+			writeKentekenNotFound(w, plate)
+			return
+		}
+		if err != nil {
+			log.Println("Error fetching voertuig history ", err)
+			writeError(w, http.StatusInternalServerError, "database error")
+			return
+		}
+
+		writeJSON(w, http.StatusOK, voertuigHistory{Kenteken: plate, Versions: versions})
+	}
+}
+
+// handleGetVoertuigRecalls serves GET /v1/voertuigen/{kenteken}/terugroepacties.
+func handleGetVoertuigRecalls(store VehicleStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := requestFormat(w, r, formatJSON); !ok {
+			return
+		}
+		plate, ok := requestKenteken(w, r)
+		if !ok {
+			return
+		}
+
+		recalls, err := store.Recalls(plate)
+		if err == nil && len(recalls.Open)+len(recalls.Closed) == 0 {
+			_, err = store.Get(plate, kentekenProjection)
+		}
+		if errors.Is(err, sql.ErrNoRows) {
+			writeKentekenNotFound(w, plate)
+			return
+		}
+		if err != nil {
+			log.Println("Error fetching terugroepacties ", err)
+			writeError(w, http.StatusInternalServerError, "database error")
+			return
+		}
+
+		writeJSON(w, http.StatusOK, recalls)
+	}
+}
+
+// voertuigKeuringen is the body of GET /v1/voertuigen/{kenteken}/keuringen.
+type voertuigKeuringen struct {
+	Kenteken  string    `json:"kenteken"`
+	Keuringen []keuring `json:"keuringen"`
+}
+
+// handleGetVoertuigKeuringen serves GET /v1/voertuigen/{kenteken}/keuringen.
+func handleGetVoertuigKeuringen(store VehicleStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := requestFormat(w, r, formatJSON); !ok {
+			return
+		}
+		plate, ok := requestKenteken(w, r)
+		if !ok {
+			return
+		}
+
+		keuringen, err := store.Keuringen(plate)
+		if err == nil && len(keuringen) == 0 {
+			_, err = store.Get(plate, kentekenProjection)
+		}
+		if errors.Is(err, sql.ErrNoRows) {
+			writeKentekenNotFound(w, plate)
+			return
+		}
+		if err != nil {
+			log.Println("Error fetching keuringen ", err)
+			writeError(w, http.StatusInternalServerError, "database error")
+			return
+		}
+
+		writeJSON(w, http.StatusOK, voertuigKeuringen{Kenteken: plate, Keuringen: keuringen})
+	}
+}
+
+// handleGetStats serves GET /v1/meta/stats: the number of vehicles, how many
+// of them are removed and the date of the newest snapshot.
+func handleGetStats(store VehicleStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := requestFormat(w, r, formatJSON); !ok {
+			return
+		}
+		stats, err := store.Stats()
+		if err != nil {
+			log.Println("Error fetching stats ", err)
+			writeError(w, http.StatusInternalServerError, "database error")
+			return
+		}
+		writeJSON(w, http.StatusOK, stats)
+	}
+}
+
+// maxRunsLimit caps limit= of GET /v1/meta/imports.
+const maxRunsLimit = 100
+
+// importRuns is the body of GET /v1/meta/imports.
+type importRuns struct {
+	LastSucceeded *importRun  `json:"last_succeeded"`
+	Runs          []importRun `json:"runs"`
+}
+
+// handleGetImports serves GET /v1/meta/imports: the newest successful import,
+// which tells how fresh the data is, and the newest runs with limit= (default
+// 20, at most 100) entries.
+func handleGetImports(store VehicleStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := requestFormat(w, r, formatJSON); !ok {
+			return
+		}
+		limit := defaultRunsLimit
+		if value := r.URL.Query().Get("limit"); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil || parsed < 1 {
+				writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid limit %q", value))
+				return
+			}
+			limit = min(parsed, maxRunsLimit)
+		}
+
+		var response importRuns
+		last, err := store.LastSucceededImportRun()
+		if err == nil {
+			response.LastSucceeded = &last
+		} else if !errors.Is(err, sql.ErrNoRows) {
+			log.Println("Error fetching import runs ", err)
+			writeError(w, http.StatusInternalServerError, "database error")
+			return
+		}
+		if response.Runs, err = store.ImportRuns(limit); err != nil {
+			log.Println("Error fetching import runs ", err)
+			writeError(w, http.StatusInternalServerError, "database error")
+			return
+		}
+		writeJSON(w, http.StatusOK, response)
+	}
+}
+
+// sodaError is the error body of the SODA endpoint, shaped like the errors
+// of the RDW open data API.
+type sodaError struct {
+	Code    string `json:"code"`
+	Error   bool   `json:"error"`
+	Message string `json:"message"`
+}
+
+// handleSodaVoertuigen serves GET /resource/m9d7-ebf2.json, a SODA compatible
+// view on voertuigen so tools written for opendata.rdw.nl can use this API by
+// changing the base URL. $limit is capped at SODA_MAX_LIMIT (default 50000).
+func handleSodaVoertuigen(store VehicleStore) http.HandlerFunc {
+	maxLimit := defaultSodaMaxLimit
+	if getEnvVar("SODA_MAX_LIMIT") != "" {
+		maxLimit = getIntEnvVar("SODA_MAX_LIMIT")
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		query, err := parseSodaQuery(r.URL.Query(), maxLimit)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, sodaError{Code: "query.compiler.malformed", Error: true, Message: err.Error()})
+			return
+		}
+
+		rows, err := store.Soda(query)
+		if err != nil {
+			log.Println("Error running SODA query ", err)
+			writeJSON(w, http.StatusInternalServerError, sodaError{Code: "internal", Error: true, Message: "database error"})
+			return
+		}
+
+		writeJSON(w, http.StatusOK, rows)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Println("Error writing response ", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, apiError{Error: message})
+}
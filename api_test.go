@@ -0,0 +1,306 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// newTestServer imports the snapshots in testdata into a new SQLite file with
+// the delta mode and serves the API on it. Between the snapshots XX123B
+// changes colour, 1TTT23 is removed, 99XXX9 is added and ZZ999Z, rejected
+// for its date in the first snapshot, is added. CDJ001 matches no sidecode.
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("DB_DRIVER", "sqlite")
+	t.Setenv("SQLITE_FILE", filepath.Join(dir, "kentekens.db"))
+	for _, snapshot := range []string{"2026-01-01", "2026-02-01"} {
+		runImport([]string{
+			"-file", filepath.Join("testdata", "voertuigen-"+snapshot+".csv"),
+			"-mode", "delta",
+			"-snapshot-date", snapshot,
+			"-rejects", filepath.Join(dir, "rejects.csv"),
+		})
+	}
+
+	store, err := openStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(newRouter(store))
+	t.Cleanup(func() {
+		server.Close()
+		store.Close()
+	})
+	return server
+}
+
+// request sends a request to the test server and returns the status and the
+// body.
+func request(t *testing.T, server *httptest.Server, method, path, accept, body string) (int, string) {
+	t.Helper()
+	req, err := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, string(data)
+}
+
+// getJSON fetches path and decodes the JSON body into v, failing the test
+// unless the status is 200.
+func getJSON(t *testing.T, server *httptest.Server, path string, v any) {
+	t.Helper()
+	status, body := request(t, server, http.MethodGet, path, "", "")
+	if status != http.StatusOK {
+		t.Fatalf("GET %s returned %d: %s", path, status, body)
+	}
+	if err := json.Unmarshal([]byte(body), v); err != nil {
+		t.Fatalf("GET %s returned %q: %v", path, body, err)
+	}
+}
+
+func TestAPIGetVoertuig(t *testing.T) {
+	server := newTestServer(t)
+
+	var voertuig map[string]any
+	getJSON(t, server, "/v1/voertuigen/xx-123-b", &voertuig)
+	if voertuig["kenteken"] != "XX123B" || voertuig["merk"] != "TESLA" || voertuig["eerste_kleur"] != "BLAUW" ||
+		voertuig["datum_eerste_toelating"] != "2020-01-15" || voertuig["catalogusprijs"] != 45000.0 {
+		t.Errorf("got %v", voertuig)
+	}
+
+	voertuig = nil
+	getJSON(t, server, "/v1/voertuigen/XX123B?fields=merk,handelsbenaming", &voertuig)
+	if len(voertuig) != 2 || voertuig["handelsbenaming"] != "MODEL 3" {
+		t.Errorf("got %v with fields=merk,handelsbenaming", voertuig)
+	}
+
+	if status, body := request(t, server, http.MethodGet, "/v1/voertuigen/XX123B?fields=kenteken,merk", "text/csv", ""); status != http.StatusOK || body != "kenteken,merk\nXX123B,TESLA\n" {
+		t.Errorf("CSV returned %d: %q", status, body)
+	}
+
+	tests := []struct {
+		path   string
+		accept string
+		status int
+	}{
+		// stored plates are found even when they match no sidecode
+		{"/v1/voertuigen/cdj-001", "", http.StatusOK},
+		{"/v1/voertuigen/BB-12-BB", "", http.StatusNotFound},
+		{"/v1/voertuigen/AA-123-B", "", http.StatusBadRequest},
+		{"/v1/voertuigen/XX123B?fields=nope", "", http.StatusBadRequest},
+		{"/v1/voertuigen/XX123B?embed=nope", "", http.StatusBadRequest},
+		{"/v1/voertuigen/XX123B", "image/png", http.StatusNotAcceptable},
+	}
+	for _, test := range tests {
+		if status, body := request(t, server, http.MethodGet, test.path, test.accept, ""); status != test.status {
+			t.Errorf("GET %s (Accept %q) returned %d, want %d: %s", test.path, test.accept, status, test.status, body)
+		}
+	}
+}
+
+func TestAPISearch(t *testing.T) {
+	server := newTestServer(t)
+
+	var page struct {
+		Data       []map[string]any `json:"data"`
+		NextCursor *string          `json:"next_cursor"`
+	}
+	getJSON(t, server, "/v1/voertuigen?merk=tesla&sort=-datum_eerste_toelating&fields=kenteken&limit=1", &page)
+	if len(page.Data) != 1 || page.Data[0]["kenteken"] != "GB123D" || page.NextCursor == nil {
+		t.Fatalf("got first page %v, cursor %v", page.Data, page.NextCursor)
+	}
+
+	cursor := *page.NextCursor
+	page.Data, page.NextCursor = nil, nil
+	getJSON(t, server, "/v1/voertuigen?merk=tesla&sort=-datum_eerste_toelating&fields=kenteken&limit=1&cursor="+cursor, &page)
+	if len(page.Data) != 1 || page.Data[0]["kenteken"] != "XX123B" || page.NextCursor != nil {
+		t.Errorf("got second page %v, cursor %v", page.Data, page.NextCursor)
+	}
+
+	if status, body := request(t, server, http.MethodGet, "/v1/voertuigen?catalogusprijs.gte=50000&fields=kenteken", "application/x-ndjson", ""); status != http.StatusOK ||
+		body != "{\"kenteken\":\"CDJ001\"}\n{\"kenteken\":\"GB123D\"}\n" {
+		t.Errorf("NDJSON search returned %d: %q", status, body)
+	}
+	if status, _ := request(t, server, http.MethodGet, "/v1/voertuigen?nope=1", "", ""); status != http.StatusBadRequest {
+		t.Errorf("unknown parameter returned %d", status)
+	}
+}
+
+func TestAPIBatch(t *testing.T) {
+	server := newTestServer(t)
+
+	const body = `{"kentekens": ["xx-123-b", "CDJ001", "BB-12-BB", "AA-123-B", "", "XX123B"]}`
+	status, data := request(t, server, http.MethodPost, "/v1/voertuigen:batch?fields=kenteken,merk", "", body)
+	if status != http.StatusOK {
+		t.Fatalf("batch returned %d: %s", status, data)
+	}
+	var response struct {
+		Found    []map[string]any `json:"found"`
+		NotFound []string         `json:"not_found"`
+		Invalid  []invalidKenteken
+	}
+	if err := json.Unmarshal([]byte(data), &response); err != nil {
+		t.Fatal(err)
+	}
+	var found []string
+	for _, voertuig := range response.Found {
+		found = append(found, voertuig["kenteken"].(string))
+	}
+	var invalid []string
+	for _, plate := range response.Invalid {
+		invalid = append(invalid, plate.Kenteken)
+	}
+	slices.Sort(invalid)
+	if !slices.Equal(found, []string{"XX123B", "CDJ001"}) || !slices.Equal(response.NotFound, []string{"BB12BB"}) || !slices.Equal(invalid, []string{"", "AA123B"}) {
+		t.Errorf("got found %v, not found %v, invalid %v", found, response.NotFound, invalid)
+	}
+
+	status, data = request(t, server, http.MethodPost, "/v1/voertuigen:batch?fields=kenteken,merk", "text/csv", body)
+	if status != http.StatusOK || data != "kenteken,merk\nXX123B,TESLA\nCDJ001,BMW\n" {
+		t.Errorf("CSV batch returned %d: %q", status, data)
+	}
+	if status, _ := request(t, server, http.MethodPost, "/v1/voertuigen:batch", "image/png", body); status != http.StatusNotAcceptable {
+		t.Errorf("batch with Accept image/png returned %d", status)
+	}
+	if status, _ := request(t, server, http.MethodPost, "/v1/voertuigen:batch", "", "{"); status != http.StatusBadRequest {
+		t.Errorf("batch with an invalid body returned %d", status)
+	}
+}
+
+func TestAPIHistory(t *testing.T) {
+	server := newTestServer(t)
+
+	type version struct {
+		ValidFrom string  `json:"valid_from"`
+		ValidTo   *string `json:"valid_to"`
+		Changes   map[string]struct {
+			Old string `json:"old"`
+			New string `json:"new"`
+		} `json:"changes"`
+	}
+	var history struct {
+		Versions []version `json:"versions"`
+	}
+	getJSON(t, server, "/v1/voertuigen/xx-123-b/history", &history)
+	if len(history.Versions) != 2 {
+		t.Fatalf("got %d versions, want 2", len(history.Versions))
+	}
+	first, second := history.Versions[0], history.Versions[1]
+	change := second.Changes["eerste_kleur"]
+	if first.ValidFrom != "2026-01-01" || first.ValidTo == nil || *first.ValidTo != "2026-02-01" ||
+		second.ValidTo != nil || len(second.Changes) != 1 || change.Old != "ROOD" || change.New != "BLAUW" {
+		t.Errorf("got versions %+v", history.Versions)
+	}
+
+	history.Versions = nil
+	getJSON(t, server, "/v1/voertuigen/1TTT23/history", &history)
+	if len(history.Versions) != 1 || history.Versions[0].ValidTo == nil {
+		t.Errorf("the removed vehicle has versions %+v", history.Versions)
+	}
+
+	tests := []struct {
+		path   string
+		accept string
+		status int
+	}{
+		{"/v1/voertuigen/BB-12-BB/history", "", http.StatusNotFound},
+		{"/v1/voertuigen/XX123B/history", "text/csv", http.StatusNotAcceptable},
+		{"/v1/voertuigen/XX123B/history", "text/csv, application/json;q=0.5", http.StatusOK},
+		{"/v1/voertuigen/XX123B/terugroepacties", "text/csv", http.StatusNotAcceptable},
+		{"/v1/voertuigen/XX123B/keuringen", "application/x-ndjson", http.StatusNotAcceptable},
+		{"/v1/meta/stats", "text/csv", http.StatusNotAcceptable},
+	}
+	for _, test := range tests {
+		if status, body := request(t, server, http.MethodGet, test.path, test.accept, ""); status != test.status {
+			t.Errorf("GET %s (Accept %q) returned %d, want %d: %s", test.path, test.accept, status, test.status, body)
+		}
+	}
+}
+
+func TestAPIMeta(t *testing.T) {
+	server := newTestServer(t)
+
+	var stats struct {
+		Total        int    `json:"total"`
+		Removed      int    `json:"removed"`
+		LastSnapshot string `json:"last_snapshot"`
+	}
+	getJSON(t, server, "/v1/meta/stats", &stats)
+	if stats.Total != 6 || stats.Removed != 1 || stats.LastSnapshot != "2026-02-01" {
+		t.Errorf("got stats %+v", stats)
+	}
+
+	type run struct {
+		ID        int64  `json:"id"`
+		Dataset   string `json:"dataset"`
+		Mode      string `json:"mode"`
+		Read      int    `json:"read"`
+		Inserted  int    `json:"inserted"`
+		Updated   int    `json:"updated"`
+		Unchanged int    `json:"unchanged"`
+		Rejected  int    `json:"rejected"`
+	}
+	var imports struct {
+		LastSucceeded *run  `json:"last_succeeded"`
+		Runs          []run `json:"runs"`
+	}
+	getJSON(t, server, "/v1/meta/imports", &imports)
+	if len(imports.Runs) != 2 || imports.LastSucceeded == nil || imports.LastSucceeded.ID != imports.Runs[0].ID {
+		t.Fatalf("got imports %+v", imports)
+	}
+	last := imports.LastSucceeded
+	if last.Dataset != "voertuigen" || last.Mode != "delta" || last.Read != 5 || last.Inserted != 2 || last.Updated != 1 || last.Unchanged != 2 {
+		t.Errorf("got last import %+v", last)
+	}
+	if first := imports.Runs[1]; first.Inserted != 4 || first.Rejected != 1 {
+		t.Errorf("got first import %+v", first)
+	}
+}
+
+func TestDecodeBatchRequest(t *testing.T) {
+	tests := []struct {
+		body string
+		want []string
+		err  bool
+	}{
+		{`{"kentekens": ["a", "b"]}`, []string{"a", "b"}, false},
+		{`{"limit": [1, 2], "Kentekens": ["a"]}`, []string{"a"}, false},
+		{`{"kentekens": []}`, []string{}, false},
+		{`{}`, nil, false},
+		{`{"kentekens": ["a", "b", "c"]}`, []string{"a", "b"}, true},
+		{`{"kentekens": ["a", 1]}`, []string{"a"}, true},
+		{`{"kentekens": "a"}`, nil, true},
+		{`["a"]`, nil, true},
+		{`{`, nil, true},
+	}
+	for _, test := range tests {
+		request, err := decodeBatchRequest(strings.NewReader(test.body), 2)
+		if (err != nil) != test.err || !slices.Equal(request.Kentekens, test.want) {
+			t.Errorf("decodeBatchRequest(%s) = %q, %v", test.body, request.Kentekens, err)
+		}
+	}
+	// the limit is checked before the rest of the list is read
+	if _, err := decodeBatchRequest(strings.NewReader(`{"kentekens": ["a", "b", "c", `), 2); !errors.Is(err, errTooManyKentekens) {
+		t.Errorf("got %v for a truncated list over the limit, want %v", err, errTooManyKentekens)
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+)
+
+// csvBatches reads the rows of an import CSV, converts them and hands them
+// out in batches of importBatchSize. A row that does not convert is written
+// to rejects instead of ending the import, until more than maxErrors rows
+// are rejected. The voertuigen and the dataset imports share it.
+type csvBatches[T any] struct {
+	reader      *csv.Reader
+	convert     func(values []string, line int) (T, error)
+	rejects     *rejectsWriter
+	rejectsPath string
+	maxErrors   int
+	stats       *importStats
+
+	// lineOffset is the number of lines before the position the reader
+	// started at, when it started halfway the file
+	lineOffset int
+	// rejected, when set, is called with every rejected row
+	rejected func(values []string)
+
+	// line is the line of the last record read, relative to the reader
+	line int
+}
+
+// run reads the CSV to the end and calls flush with every full batch and the
+// remaining records.
+func (b *csvBatches[T]) run(flush func(batch []T)) error {
+	batchSize := importBatchSize()
+	var batch []T
+	for {
+		values, err := b.reader.Read()
+		if err == io.EOF {
+			break
+		}
+		b.stats.read.Add(1)
+
+		var record T
+		var parseErr *csv.ParseError
+		if err == nil {
+			b.line, _ = b.reader.FieldPos(0)
+			record, err = b.convert(values, b.lineOffset+b.line)
+		} else if errors.As(err, &parseErr) {
+			parseErr.StartLine += b.lineOffset
+			parseErr.Line += b.lineOffset
+		} else {
+			return fmt.Errorf("error reading a record: %w", err)
+		}
+		if err != nil {
+			log.Println("Rejecting row: ", err)
+			if err := b.rejects.write(values, err); err != nil {
+				return fmt.Errorf("error writing rejects file: %w", err)
+			}
+			if b.stats.rejected.Add(1) > int64(b.maxErrors) {
+				return fmt.Errorf("more than %d rows rejected, aborting the import, see %s", b.maxErrors, b.rejectsPath)
+			}
+			if b.rejected != nil {
+				b.rejected(values)
+			}
+			continue
+		}
+
+		batch = append(batch, record)
+		if len(batch) >= batchSize {
+			flush(batch)
+			batch = nil // Start a new batch
+		}
+	}
+
+	// Process any remaining records that did not make a full batch
+	if len(batch) > 0 {
+		flush(batch)
+	}
+	return nil
+}
+
+// batchWriter runs the writes of batches concurrently, at most 100 at a time.
+type batchWriter struct {
+	wg        sync.WaitGroup
+	semaphore chan struct{}
+}
+
+func newBatchWriter() *batchWriter {
+	return &batchWriter{semaphore: make(chan struct{}, 100)}
+}
+
+// write runs f in a goroutine once a slot is free.
+func (w *batchWriter) write(f func()) {
+	w.wg.Add(1)
+	w.semaphore <- struct{}{} // Acquire a slot in the semaphore
+	go func() {
+		defer w.wg.Done()
+		f()
+		<-w.semaphore // Release a slot in the semaphore
+	}()
+}
+
+// wait waits for all writes to finish.
+func (w *batchWriter) wait() {
+	w.wg.Wait()
+}
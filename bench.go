@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// runBench measures how fast the store writes voertuigen. It reads -rows
+// records from the CSV and writes them into the staging table once for every
+// -rows-per-statement value, in batches of IMPORT_BATCH_SIZE on a single
+// connection. 1 row per statement is the writer as it was before multi-row
+// INSERTs. Only the MySQL store uses the setting, other stores are measured
+// once.
+func runBench(args []string) {
+	flags := flag.NewFlagSet("bench", flag.ExitOnError)
+	path := flags.String("file", getEnvVar("CSV_FILE"), "path of the RDW CSV to read the records from")
+	count := flags.Int("rows", 20000, "number of records to write")
+	perStatement := flags.String("rows-per-statement", "1,100,"+strconv.Itoa(defaultRowsPerStatement), "comma separated rows per INSERT statement to compare")
+	flags.Parse(args)
+
+	records, err := readBenchRecords(*path, *count)
+	if err != nil {
+		log.Fatal("Error reading records ", err)
+	}
+
+	store, err := openStore()
+	if err != nil {
+		log.Fatal("Error connecting to the database ", err)
+	}
+	mysql, isMySQL := store.(*mysqlStore)
+
+	snapshot := time.Now().UTC().Truncate(24 * time.Hour)
+	batchSize := importBatchSize()
+	for _, value := range strings.Split(*perStatement, ",") {
+		rowsPerStatement, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || rowsPerStatement < 1 {
+			log.Fatalf("Invalid rows per statement %q", value)
+		}
+		if isMySQL {
+			mysql.rowsPerStatement = rowsPerStatement
+		}
+
+		staging, err := store.CreateStaging(voertuigenTable)
+		if err != nil {
+			log.Fatal("Error creating staging table ", err)
+		}
+		var failed int64
+		start := time.Now()
+		for i := 0; i < len(records); i += batchSize {
+			result, err := store.BulkWrite(staging, records[i:min(i+batchSize, len(records))], "insert", snapshot)
+			if err != nil {
+				log.Fatal("Error writing batch ", err)
+			}
+			failed += result.failed
+		}
+		elapsed := time.Since(start)
+		if err := store.DropStaging(staging); err != nil {
+			log.Println("Error dropping staging table ", err)
+		}
+
+		measured := fmt.Sprintf("wrote %d records in %s, %.0f records/s, %d failed",
+			len(records), elapsed.Round(time.Millisecond), float64(len(records))/elapsed.Seconds(), failed)
+		if !isMySQL {
+			log.Printf("%s (only the MySQL store writes multiple rows per statement)", measured)
+			break
+		}
+		log.Printf("%d rows per statement: %s", rowsPerStatement, measured)
+	}
+}
+
+// readBenchRecords reads up to count records from the CSV, skipping rows that
+// cannot be converted. A CSV that cannot be read, like a truncated file, is an
+// error.
+func readBenchRecords(path string, count int) ([]RDWRecord, error) {
+	source, err := openImportSource(path, false)
+	if err != nil {
+		return nil, err
+	}
+	defer source.Close()
+
+	reader := csv.NewReader(source)
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+	columns, err := newCSVColumnMap(header)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []RDWRecord
+	for len(records) < count {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+		if record, err := columns.record(row, line); err == nil {
+			records = append(records, record)
+		}
+	}
+	log.Printf("Read %d records from %s", len(records), path)
+	return records, nil
+}
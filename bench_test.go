@@ -0,0 +1,123 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// benchRecords returns n distinct vehicles with a few columns filled in.
+func benchRecords(n int) []RDWRecord {
+	records := make([]RDWRecord, n)
+	for i := range records {
+		records[i] = RDWRecord{
+			Kenteken:             fmt.Sprintf("BN%04dX", i),
+			Voertuigsoort:        "Personenauto",
+			Merk:                 "TESLA",
+			Handelsbenaming:      "MODEL 3",
+			AantalZitplaatsen:    NullInt{sql.NullInt64{Int64: 5, Valid: true}},
+			DatumEersteToelating: NullDate{sql.NullTime{Time: time.Date(2020, 1, 1+i%365, 0, 0, 0, 0, time.UTC), Valid: true}},
+		}
+	}
+	return records
+}
+
+// BenchmarkBulkWrite measures one batch of IMPORT_BATCH_SIZE records written
+// into an empty staging table (insert) and over the same records again
+// (upsert) on a SQLite file.
+func BenchmarkBulkWrite(b *testing.B) {
+	store, err := openSQLiteStore(filepath.Join(b.TempDir(), "bench.db"))
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer store.Close()
+	records := benchRecords(importBatchSize())
+	snapshot := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for _, mode := range []string{"insert", "upsert"} {
+		b.Run(mode, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				staging, err := store.CreateStaging(voertuigenTable)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if mode == "upsert" {
+					if _, err := store.BulkWrite(staging, records, "insert", snapshot); err != nil {
+						b.Fatal(err)
+					}
+				}
+				b.StartTimer()
+
+				result, err := store.BulkWrite(staging, records, mode, snapshot)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if result.failed > 0 {
+					b.Fatalf("%d records failed", result.failed)
+				}
+			}
+			b.ReportMetric(float64(b.N*len(records))/b.Elapsed().Seconds(), "records/s")
+		})
+	}
+}
+
+// BenchmarkMySQLBulkWrite measures one batch of IMPORT_BATCH_SIZE records
+// inserted into the staging table of the MySQL database in DB_HOST and
+// DB_NAME, at 1 row per statement (the writer before multi-row INSERTs), 100
+// and 500. It is skipped without a database.
+func BenchmarkMySQLBulkWrite(b *testing.B) {
+	if dbHost == "" || dbName == "" {
+		b.Skip("DB_HOST and DB_NAME are not set")
+	}
+	store, err := openMySQLStore()
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer store.Close()
+	records := benchRecords(importBatchSize())
+	snapshot := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for _, rowsPerStatement := range []int{1, 100, 500} {
+		b.Run(fmt.Sprintf("rows-per-statement=%d", rowsPerStatement), func(b *testing.B) {
+			store.rowsPerStatement = rowsPerStatement
+			var staging string
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				if staging, err = store.CreateStaging(voertuigenTable); err != nil {
+					b.Fatal(err)
+				}
+				b.StartTimer()
+
+				result, err := store.BulkWrite(staging, records, "insert", snapshot)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if result.failed > 0 {
+					b.Fatalf("%d records failed", result.failed)
+				}
+			}
+			b.ReportMetric(float64(b.N*len(records))/b.Elapsed().Seconds(), "records/s")
+			if err := store.DropStaging(staging); err != nil {
+				b.Error(err)
+			}
+		})
+	}
+}
+
+func TestReadBenchRecordsTruncated(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "truncated.csv")
+	header := strings.Split(voertuigColumnList(), ", ")
+	content := strings.Join(header, ",") + "\nXX123B,Personenauto,\"TES"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := readBenchRecords(path, 10); err == nil {
+		t.Error("expected an error for the truncated file")
+	}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// importState is the import_state row of an unfinished import: the source
+// file, the position in it up to which every batch has been committed and the
+// counts of the rows before that position.
+type importState struct {
+	table    string
+	fileHash string
+	mode     string
+	snapshot time.Time
+
+	offset int64
+	line   int64
+
+	read      int64
+	rejected  int64
+	failed    int64
+	inserted  int64
+	updated   int64
+	unchanged int64
+}
+
+// fetches the state of the unfinished import into table, sql.ErrNoRows when there is none
+func getImportState(db *sql.DB, table string) (importState, error) {
+	state := importState{table: table}
+	var snapshot NullDate
+	err := db.QueryRow("SELECT file_sha256, mode, snapshot_date, byte_offset, line_number, read_rows, rejected_rows, failed_rows, inserted_rows, updated_rows, unchanged_rows FROM import_state WHERE target_table = ?", table).
+		Scan(&state.fileHash, &state.mode, &snapshot, &state.offset, &state.line,
+			&state.read, &state.rejected, &state.failed, &state.inserted, &state.updated, &state.unchanged)
+	state.snapshot = snapshot.Time
+	return state, err
+}
+
+// replaces the state of the import into state.table
+func saveImportState(db *sql.DB, state importState) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM import_state WHERE target_table = ?", state.table); err != nil {
+		return err
+	}
+	_, err = tx.Exec("INSERT INTO import_state (target_table, file_sha256, mode, snapshot_date, byte_offset, line_number, read_rows, rejected_rows, failed_rows, inserted_rows, updated_rows, unchanged_rows, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		state.table, state.fileHash, state.mode, state.snapshot, state.offset, state.line,
+		state.read, state.rejected, state.failed, state.inserted, state.updated, state.unchanged, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// deletes the state of the import into table
+func deleteImportState(db *sql.DB, table string) error {
+	_, err := db.Exec("DELETE FROM import_state WHERE target_table = ?", table)
+	return err
+}
+
+// fileSHA256 returns the hex SHA-256 checksum of the file at path.
+func fileSHA256(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// maxPendingCheckpoints is how many batches the import may run ahead of the
+// saved position.
+const maxPendingCheckpoints = 100
+
+// checkpointer saves the position an import has committed up to in
+// import_state. Batches are written concurrently and may commit out of order,
+// so the position only moves past a batch once the batches before it have
+// committed as well. A nil checkpointer saves nothing.
+type checkpointer struct {
+	store importStore
+
+	mu      sync.Mutex
+	moved   *sync.Cond
+	state   importState
+	pending []*checkpoint
+}
+
+// checkpoint is the position after one batch and the rows read and rejected
+// up to it.
+type checkpoint struct {
+	offset   int64
+	line     int64
+	read     int64
+	rejected int64
+
+	committed bool
+	result    writeResult
+}
+
+// newCheckpointer saves state as the starting point of the import.
+func newCheckpointer(store importStore, state importState) (*checkpointer, error) {
+	if err := store.SaveImportState(state); err != nil {
+		return nil, err
+	}
+	c := &checkpointer{store: store, state: state}
+	c.moved = sync.NewCond(&c.mu)
+	return c, nil
+}
+
+// add registers the next batch, which ends at offset and line. Batches must be
+// added in the order they were read. It waits while maxPendingCheckpoints
+// batches are pending, so a batch that keeps losing the race for a database
+// connection does not hold the saved position back indefinitely.
+func (c *checkpointer) add(offset, line, read, rejected int64) *checkpoint {
+	if c == nil {
+		return nil
+	}
+	batch := &checkpoint{offset: offset, line: line, read: read, rejected: rejected}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for len(c.pending) >= maxPendingCheckpoints {
+		c.moved.Wait()
+	}
+	c.pending = append(c.pending, batch)
+	return batch
+}
+
+// commit records that batch was committed with result, and saves the new
+// position when every batch before it is committed too.
+func (c *checkpointer) commit(batch *checkpoint, result writeResult) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	batch.committed, batch.result = true, result
+	moved := false
+	for len(c.pending) > 0 && c.pending[0].committed {
+		done := c.pending[0]
+		c.pending = c.pending[1:]
+		c.state.offset, c.state.line = done.offset, done.line
+		c.state.read, c.state.rejected = done.read, done.rejected
+		c.state.failed += done.result.failed
+		c.state.inserted += done.result.inserted
+		c.state.updated += done.result.updated
+		c.state.unchanged += done.result.unchanged
+		moved = true
+	}
+	if !moved {
+		return
+	}
+	c.moved.Broadcast()
+	if err := c.store.SaveImportState(c.state); err != nil {
+		log.Println("Error saving import checkpoint ", err)
+	}
+}
+
+// finish deletes the state once the import completed.
+func (c *checkpointer) finish() {
+	if c == nil {
+		return
+	}
+	if err := c.store.DeleteImportState(c.state.table); err != nil {
+		log.Println("Error deleting import state ", err)
+	}
+}
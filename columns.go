@@ -0,0 +1,392 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/FayKn/RDW-Kenteken-Api/kenteken"
+)
+
+// voertuigColumn binds a column of the voertuigen table to the RDWRecord field
+// it is stored in, so queries can be built and scanned without repeating the
+// full column list.
+type voertuigColumn struct {
+	name  string
+	field func(r *RDWRecord) any
+}
+
+// voertuigColumns lists every column of the voertuigen table in schema order.
+var voertuigColumns = []voertuigColumn{
+	{"kenteken", func(r *RDWRecord) any { return &r.Kenteken }},
+	{"voertuigsoort", func(r *RDWRecord) any { return &r.Voertuigsoort }},
+	{"merk", func(r *RDWRecord) any { return &r.Merk }},
+	{"handelsbenaming", func(r *RDWRecord) any { return &r.Handelsbenaming }},
+	{"vervaldatum_apk", func(r *RDWRecord) any { return &r.VervaldatumApk }},
+	{"datum_tenaamstelling", func(r *RDWRecord) any { return &r.DatumTenaamstelling }},
+	{"bruto_bpm", func(r *RDWRecord) any { return &r.BrutoBpm }},
+	{"inrichting", func(r *RDWRecord) any { return &r.Inrichting }},
+	{"aantal_zitplaatsen", func(r *RDWRecord) any { return &r.AantalZitplaatsen }},
+	{"eerste_kleur", func(r *RDWRecord) any { return &r.EersteKleur }},
+	{"tweede_kleur", func(r *RDWRecord) any { return &r.TweedeKleur }},
+	{"aantal_cilinders", func(r *RDWRecord) any { return &r.AantalCilinders }},
+	{"cilinderinhoud", func(r *RDWRecord) any { return &r.Cilinderinhoud }},
+	{"massa_ledig_voertuig", func(r *RDWRecord) any { return &r.MassaLedigVoertuig }},
+	{"toegestane_maximum_massa_voertuig", func(r *RDWRecord) any { return &r.ToegestaneMaximumMassaVoertuig }},
+	{"massa_rijklaar", func(r *RDWRecord) any { return &r.MassaRijklaar }},
+	{"maximum_massa_trekken_ongeremd", func(r *RDWRecord) any { return &r.MaximumTrekkenMassaOngeremd }},
+	{"maximum_trekken_massa_geremd", func(r *RDWRecord) any { return &r.MaximumTrekkenMassaGeremd }},
+	{"datum_eerste_toelating", func(r *RDWRecord) any { return &r.DatumEersteToelating }},
+	{"datum_eerste_tenaamstelling_in_nederland", func(r *RDWRecord) any { return &r.DatumEersteTenaamstallingNL }},
+	{"wacht_op_keuren", func(r *RDWRecord) any { return &r.WachtOpKeuren }},
+	{"catalogusprijs", func(r *RDWRecord) any { return &r.Catalogusprijs }},
+	{"wam_verzekerd", func(r *RDWRecord) any { return &r.WamVerzekerd }},
+	{"maximale_constructiesnelheid", func(r *RDWRecord) any { return &r.MaxSnelheid }},
+	{"laadvermogen", func(r *RDWRecord) any { return &r.Laadvermogen }},
+	{"oplegger_geremd", func(r *RDWRecord) any { return &r.OpleggerGeremd }},
+	{"aanhangwagen_autonoom_geremd", func(r *RDWRecord) any { return &r.AanhangwagenAutonoomGeremd }},
+	{"aanhangwagen_middenas_geremd", func(r *RDWRecord) any { return &r.AanhangwagenMiddenasGeremd }},
+	{"aantal_staanplaatsen", func(r *RDWRecord) any { return &r.AantalStaanplaatsen }},
+	{"aantal_deuren", func(r *RDWRecord) any { return &r.AantalDeuren }},
+	{"aantal_wielen", func(r *RDWRecord) any { return &r.AantalWielen }},
+	{"afstand_hart_koppeling_tot_achterzijde_voertuig", func(r *RDWRecord) any { return &r.AfstandHartKoppelingTotAchterzijdeVoertuig }},
+	{"afstand_voorzijde_voertuig_tot_hart_koppeling", func(r *RDWRecord) any { return &r.AfstandVoorzijdeVoertuigTotHartKoppeling }},
+	{"afwijkende_maximum_snelheid", func(r *RDWRecord) any { return &r.AfwijkendeMaximumSnelheid }},
+	{"lengte", func(r *RDWRecord) any { return &r.Lengte }},
+	{"breedte", func(r *RDWRecord) any { return &r.Breedte }},
+	{"europese_voertuigcategorie", func(r *RDWRecord) any { return &r.EuropeseVoertuigCategorie }},
+	{"europese_voertuigcategorie_toevoeging", func(r *RDWRecord) any { return &r.EuropeseVoertuigCategorieToevoeging }},
+	{"europese_uitvoeringcategorie_toevoeging", func(r *RDWRecord) any { return &r.EuropeseUitvoeringcategorieToevoeging }},
+	{"plaats_chassisnummer", func(r *RDWRecord) any { return &r.PlaatsChassisnummer }},
+	{"technische_max_massa_voertuig", func(r *RDWRecord) any { return &r.TechnischeMaxMassaVoertuig }},
+	{"type", func(r *RDWRecord) any { return &r.Type }},
+	{"type_gasinstallatie", func(r *RDWRecord) any { return &r.TypeGasinstallatie }},
+	{"typegoedkeuringsnummer", func(r *RDWRecord) any { return &r.Typegoedkeuringsnummer }},
+	{"variant", func(r *RDWRecord) any { return &r.Variant }},
+	{"uitvoering", func(r *RDWRecord) any { return &r.Uitvoering }},
+	{"volgnummer_wijziging_eu_typegoedkeuring", func(r *RDWRecord) any { return &r.VolgnummerWijzigingEuTypegoedkeuring }},
+	{"vermogen_massarijklaar", func(r *RDWRecord) any { return &r.VermoegenMassarijklaar }},
+	{"wielbasis", func(r *RDWRecord) any { return &r.Wielbasis }},
+	{"export_indicator", func(r *RDWRecord) any { return &r.ExportIndicator }},
+	{"openstaande_terugroepactie_indicator", func(r *RDWRecord) any { return &r.OpenstaandeTerugroepactieIndicator }},
+	{"vervaldatum_tachograaf", func(r *RDWRecord) any { return &r.VervaldatumTachograaf }},
+	{"taxi_indicator", func(r *RDWRecord) any { return &r.TaxiIndicator }},
+	{"maximum_massa_samenstelling", func(r *RDWRecord) any { return &r.MaximumMassaSamenstelling }},
+	{"aantal_rolstoelplaatsen", func(r *RDWRecord) any { return &r.AantalRolstoelplaatsen }},
+	{"maximum_ondersteunende_snelheid", func(r *RDWRecord) any { return &r.MaximumOndersteunendeSnelheid }},
+	{"jaar_laatste_registratie_tellerstand", func(r *RDWRecord) any { return &r.JaarLaatsteRegistratieTellerstand }},
+	{"tellerstandoordeel", func(r *RDWRecord) any { return &r.Tellerstandoordeel }},
+	{"code_toelichting_tellerstandoordeel", func(r *RDWRecord) any { return &r.CodeToelichtingTellerstandoordeel }},
+	{"tenaamstellen_mogelijk", func(r *RDWRecord) any { return &r.TenaamstellenMogelijk }},
+	{"vervaldatum_apk_dt", func(r *RDWRecord) any { return &r.VervaldatumApkDt }},
+	{"datum_tenaamstelling_dt", func(r *RDWRecord) any { return &r.DatumTenaamstellingDt }},
+	{"datum_eerste_toelating_dt", func(r *RDWRecord) any { return &r.DatumEersteToelatingDt }},
+	{"datum_eerste_tenaamstelling_in_nederland_dt", func(r *RDWRecord) any { return &r.DatumEersteTenaamstellingInNederlandDt }},
+	{"vervaldatum_tachograaf_dt", func(r *RDWRecord) any { return &r.VervaldatumTachograafDt }},
+	{"maximum_last_onder_de_vooras_sen_tezamen_koppeling", func(r *RDWRecord) any { return &r.MaximumLastOnderDeVoorasSenTezamenKoppeling }},
+	{"type_remsysteem_voertuig_code", func(r *RDWRecord) any { return &r.TypeRemsysteemVoertuigCode }},
+	{"rupsonderstelconfiguratiecode", func(r *RDWRecord) any { return &r.Rupsonderstelconfiguratiecode }},
+	{"wielbasis_voertuig_minimum", func(r *RDWRecord) any { return &r.WielbasisVoertuigMinimum }},
+	{"wielbasis_voertuig_maximum", func(r *RDWRecord) any { return &r.WielbasisVoertuigMaximum }},
+	{"lengte_voertuig_minimum", func(r *RDWRecord) any { return &r.LengteVoertuigMinimum }},
+	{"lengte_voertuig_maximum", func(r *RDWRecord) any { return &r.LengteVoertuigMaximum }},
+	{"breedte_voertuig_minimum", func(r *RDWRecord) any { return &r.BreedteVoertuigMinimum }},
+	{"breedte_voertuig_maximum", func(r *RDWRecord) any { return &r.BreedteVoertuigMaximum }},
+	{"hoogte_voertuig", func(r *RDWRecord) any { return &r.HoogteVoertuig }},
+	{"hoogte_voertuig_minimum", func(r *RDWRecord) any { return &r.HoogteVoertuigMinimum }},
+	{"hoogte_voertuig_maximum", func(r *RDWRecord) any { return &r.HoogteVoertuigMaximum }},
+	{"massa_bedrijfsklaar_minimaal", func(r *RDWRecord) any { return &r.MassaBedrijfsklaarMinimaal }},
+	{"massa_bedrijfsklaar_maximaal", func(r *RDWRecord) any { return &r.MassaBedrijfsklaarMaximaal }},
+	{"technisch_toelaatbaar_massa_koppelpunt", func(r *RDWRecord) any { return &r.TechnischToelaatbaarMassaKoppelpunt }},
+	{"maximum_massa_technisch_maximaal", func(r *RDWRecord) any { return &r.MaximumMassaTechnischMaximaal }},
+	{"maximum_massa_technisch_minimaal", func(r *RDWRecord) any { return &r.MaximumMassaTechnischMinimaal }},
+	{"subcategorie_nederland", func(r *RDWRecord) any { return &r.SubcategorieNederland }},
+	{"verticale_belasting_koppelpunt_getrokken_voertuig", func(r *RDWRecord) any { return &r.VerticaleBelastingKoppelpuntGetrokkenVoertuig }},
+	{"zuinigheidsclassificatie", func(r *RDWRecord) any { return &r.Zuinigheidsclassificatie }},
+	{"registratie_datum_goedkeuring_afschrijvingsmoment_bpm", func(r *RDWRecord) any { return &r.RegistratieDatumGoedkeuringAfschrijvingsmomentBpm }},
+	{"registratie_datum_goedkeuring_afschrijvingsmoment_bpm_dt", func(r *RDWRecord) any { return &r.RegistratieDatumGoedkeuringAfschrijvingsmomentBpmDt }},
+	{"gem_lading_wrde", func(r *RDWRecord) any { return &r.GemLadingWrde }},
+	{"aerodyn_voorz", func(r *RDWRecord) any { return &r.AerodynVoorz }},
+	{"massa_alt_aandr", func(r *RDWRecord) any { return &r.MassaAltAandr }},
+	{"verl_cab_ind", func(r *RDWRecord) any { return &r.VerlCabInd }},
+	{"api_gekentekende_voertuigen_assen", func(r *RDWRecord) any { return &r.ApiGekentekendeVoertuigenAssen }},
+	{"api_gekentekende_voertuigen_brandstof", func(r *RDWRecord) any { return &r.ApiGekentekendeVoertuigenBrandstof }},
+	{"api_gekentekende_voertuigen_carrosserie", func(r *RDWRecord) any { return &r.ApiGekentekendeVoertuigenCarrosserie }},
+	{"api_gekentekende_voertuigen_carrosserie_specifiek", func(r *RDWRecord) any { return &r.ApiGekentekendeVoertuigenCarrosserieSpecifiek }},
+	{"api_gekentekende_voertuigen_voertuigklasse", func(r *RDWRecord) any { return &r.ApiGekentekendeVoertuigenVoertuigklasse }},
+}
+
+// requiredColumns must be present in the CSV header, an import without them
+// would only produce useless rows.
+var requiredColumns = []string{"kenteken", "voertuigsoort", "merk"}
+
+// voertuigColumnList returns the comma separated column names for use in a
+// SELECT or INSERT statement.
+func voertuigColumnList() string {
+	names := make([]string, len(voertuigColumns))
+	for i, column := range voertuigColumns {
+		names[i] = column.name
+	}
+	return strings.Join(names, ", ")
+}
+
+// fields returns pointers to every field of the record in column order, usable
+// both as Scan destinations and as statement arguments.
+func (r *RDWRecord) fields() []any {
+	dest := make([]any, len(voertuigColumns))
+	for i, column := range voertuigColumns {
+		dest[i] = column.field(r)
+	}
+	return dest
+}
+
+// voertuigMetaColumns are stored next to the RDW columns by the importer:
+// the hash of the record and the snapshot it was last seen in.
+var voertuigMetaColumns = []string{"row_hash", "snapshot_date"}
+
+// voertuigInsertColumns returns the columns the importer writes: the RDW
+// columns followed by the meta columns.
+func voertuigInsertColumns() []string {
+	columns := make([]string, len(voertuigColumns))
+	for i, column := range voertuigColumns {
+		columns[i] = column.name
+	}
+	return slices.Concat(columns, voertuigMetaColumns)
+}
+
+// insertVoertuigSQL returns a single row INSERT statement for all columns into
+// table, which is voertuigen or one of its staging copies. The arguments are
+// the record fields followed by the meta columns.
+func insertVoertuigSQL(table string) string {
+	return insertVoertuigRowsSQL(table, 1)
+}
+
+// insertVoertuigRowsSQL returns an INSERT statement for rows records, with
+// the arguments of every record after each other.
+func insertVoertuigRowsSQL(table string, rows int) string {
+	return insertRowsSQL(table, voertuigInsertColumns(), rows)
+}
+
+// voertuigArgs returns the statement arguments of insertVoertuigSQL and
+// upsertVoertuigSQL for a record: its fields followed by the meta columns.
+func voertuigArgs(record *RDWRecord, snapshot time.Time) []any {
+	return append(record.fields(), record.hash(), snapshot)
+}
+
+// upsertVoertuigSQL returns an INSERT that updates every column of an existing
+// vehicle with the same kenteken, bringing back vehicles marked as removed.
+func upsertVoertuigSQL(table string) string {
+	return insertVoertuigSQL(table) + onDuplicateKeyUpdateSQL()
+}
+
+// onDuplicateKeyUpdateSQL is the clause of upsertVoertuigSQL, which also
+// works after a multi-row INSERT.
+func onDuplicateKeyUpdateSQL() string {
+	updates := make([]string, 0, len(voertuigColumns)+len(voertuigMetaColumns))
+	for _, column := range voertuigColumns[1:] {
+		updates = append(updates, column.name+" = VALUES("+column.name+")")
+	}
+	for _, column := range voertuigMetaColumns {
+		updates = append(updates, column+" = VALUES("+column+")")
+	}
+	updates = append(updates, "removed_at = NULL")
+	return " ON DUPLICATE KEY UPDATE " + strings.Join(updates, ", ")
+}
+
+// onConflictUpdateSQL is the ON DUPLICATE KEY UPDATE of upsertVoertuigSQL in
+// the ON CONFLICT syntax of SQLite and PostgreSQL.
+func onConflictUpdateSQL() string {
+	updates := make([]string, 0, len(voertuigColumns)+len(voertuigMetaColumns))
+	for _, column := range voertuigColumns[1:] {
+		updates = append(updates, column.name+" = excluded."+column.name)
+	}
+	for _, column := range voertuigMetaColumns {
+		updates = append(updates, column+" = excluded."+column)
+	}
+	updates = append(updates, "removed_at = NULL")
+	return " ON CONFLICT (kenteken) DO UPDATE SET " + strings.Join(updates, ", ")
+}
+
+// fieldString returns the canonical text form of a record field, ok is false
+// for NULL.
+func fieldString(field any) (value string, ok bool) {
+	switch field := field.(type) {
+	case *string:
+		return *field, true
+	case *NullInt:
+		return strconv.FormatInt(field.Int64, 10), field.Valid
+	case *NullDecimal:
+		// every NullDecimal field is a DECIMAL(10, 2) column, compare at that
+		// precision
+		return strconv.FormatFloat(field.Float64, 'f', 2, 64), field.Valid
+	case *NullDate:
+		return field.Time.Format(time.DateOnly), field.Valid
+	default:
+		panic(fmt.Sprintf("unsupported field type %T", field))
+	}
+}
+
+// hash returns the SHA-256 of all fields, used to detect changed vehicles
+// without comparing every column.
+func (r *RDWRecord) hash() string {
+	h := sha256.New()
+	for _, field := range r.fields() {
+		value, ok := fieldString(field)
+		if !ok {
+			value = "\x00"
+		}
+		io.WriteString(h, value)
+		h.Write([]byte{0x1f})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// changedColumns returns the names of the columns that differ between r and
+// other.
+func (r *RDWRecord) changedColumns(other *RDWRecord) []string {
+	var changed []string
+	theirs := other.fields()
+	for i, field := range r.fields() {
+		value, ok := fieldString(field)
+		otherValue, otherOk := fieldString(theirs[i])
+		if value != otherValue || ok != otherOk {
+			changed = append(changed, voertuigColumns[i].name)
+		}
+	}
+	return changed
+}
+
+// csvColumnMap maps the columns of an RDW CSV onto voertuigColumns, so the
+// import keeps working when the RDW reorders, adds or drops columns.
+type csvColumnMap struct {
+	// indices holds the CSV index for every entry of voertuigColumns, -1 when
+	// the column is missing from the file.
+	indices []int
+}
+
+// newCSVColumnMap builds the column map from the header row.
+func newCSVColumnMap(header []string) (*csvColumnMap, error) {
+	names := make([]string, len(voertuigColumns))
+	for i, column := range voertuigColumns {
+		names[i] = column.name
+	}
+
+	indices, err := mapCSVHeader(header, names, requiredColumns)
+	if err != nil {
+		return nil, err
+	}
+	return &csvColumnMap{indices: indices}, nil
+}
+
+// mapCSVHeader returns the position of every column name in the header, -1
+// when it is missing. Unknown and missing optional columns are logged,
+// missing required columns are an error.
+func mapCSVHeader(header, names, required []string) ([]int, error) {
+	positions := make(map[string]int, len(header))
+	for i, name := range header {
+		positions[normalizeColumnName(name)] = i
+	}
+
+	indices := make([]int, len(names))
+	known := make(map[string]bool, len(names))
+	var missing []string
+	for i, name := range names {
+		known[name] = true
+		index, ok := positions[name]
+		if !ok {
+			index = -1
+			missing = append(missing, name)
+		}
+		indices[i] = index
+	}
+
+	for _, name := range header {
+		if !known[normalizeColumnName(name)] && name != rejectErrorColumn {
+			log.Printf("Ignoring unknown CSV column %q", name)
+		}
+	}
+
+	var missingRequired []string
+	for _, name := range missing {
+		if slices.Contains(required, name) {
+			missingRequired = append(missingRequired, name)
+		} else {
+			log.Printf("CSV column %q is missing, leaving it empty", name)
+		}
+	}
+	if len(missingRequired) > 0 {
+		return nil, fmt.Errorf("required columns missing from CSV header: %s", strings.Join(missingRequired, ", "))
+	}
+
+	return indices, nil
+}
+
+// record converts a CSV row into a typed RDWRecord. Every value that cannot be
+// converted is reported as a *rowError for the given line.
+func (m *csvColumnMap) record(row []string, line int) (RDWRecord, error) {
+	var record RDWRecord
+	var errs []error
+	for i, column := range voertuigColumns {
+		index := m.indices[i]
+		if index < 0 || index >= len(row) {
+			continue
+		}
+		if err := setField(column.field(&record), column.name, row[index]); err != nil {
+			errs = append(errs, &rowError{Line: line, Column: column.name, Value: row[index], Err: err})
+		}
+	}
+	record.Kenteken = kenteken.Normalize(record.Kenteken)
+	return record, errors.Join(errs...)
+}
+
+// kenteken returns the normalized kenteken of a raw CSV row, or an empty
+// string when the row has no kenteken column.
+func (m *csvColumnMap) kenteken(row []string) string {
+	index := m.indices[0] // kenteken is the first of voertuigColumns
+	if index < 0 || index >= len(row) {
+		return ""
+	}
+	return kenteken.Normalize(row[index])
+}
+
+// setField parses value into the field dest points to. Date columns ending in
+// _dt use the ISO 8601 format, the others the RDW yyyymmdd format.
+func setField(dest any, column, value string) error {
+	var err error
+	switch field := dest.(type) {
+	case *string:
+		*field = value
+	case *NullInt:
+		*field, err = stringToInt(value)
+	case *NullDecimal:
+		*field, err = stringToDecimal(value)
+	case *NullDate:
+		if strings.HasSuffix(column, "_dt") {
+			*field, err = parseISO8601Date(value)
+		} else {
+			*field, err = parseDateRdwFormat(value)
+		}
+	default:
+		panic(fmt.Sprintf("unsupported field type %T for column %s", dest, column))
+	}
+	return err
+}
+
+// normalizeColumnName turns both API style headers ("vervaldatum_apk") and
+// export style headers ("Vervaldatum APK", "Gem. lading wrde") into the column
+// name: lower case, with every run of other characters than letters and
+// digits replaced by a single underscore.
+func normalizeColumnName(name string) string {
+	words := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(words, "_")
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// rdwExportHeader is the header row of the Gekentekende_voertuigen CSV as
+// exported from opendata.rdw.nl.
+var rdwExportHeader = []string{
+	"\ufeffKenteken", "Voertuigsoort", "Merk", "Handelsbenaming", "Vervaldatum APK", "Datum tenaamstelling", "Bruto BPM",
+	"Inrichting", "Aantal zitplaatsen", "Eerste kleur", "Tweede kleur", "Aantal cilinders", "Cilinderinhoud",
+	"Massa ledig voertuig", "Toegestane maximum massa voertuig", "Massa rijklaar", "Maximum massa trekken ongeremd",
+	"Maximum trekken massa geremd", "Datum eerste toelating", "Datum eerste tenaamstelling in Nederland", "Wacht op keuren",
+	"Catalogusprijs", "WAM verzekerd", "Maximale constructiesnelheid", "Laadvermogen", "Oplegger geremd",
+	"Aanhangwagen autonoom geremd", "Aanhangwagen middenas geremd", "Aantal staanplaatsen", "Aantal deuren", "Aantal wielen",
+	"Afstand hart koppeling tot achterzijde voertuig", "Afstand voorzijde voertuig tot hart koppeling",
+	"Afwijkende maximum snelheid", "Lengte", "Breedte", "Europese voertuigcategorie", "Europese voertuigcategorie toevoeging",
+	"Europese uitvoeringcategorie toevoeging", "Plaats chassisnummer", "Technische max. massa voertuig", "Type",
+	"Type gasinstallatie", "Typegoedkeuringsnummer", "Variant", "Uitvoering", "Volgnummer wijziging EU typegoedkeuring",
+	"Vermogen massarijklaar", "Wielbasis", "Export indicator", "Openstaande terugroepactie indicator",
+	"Vervaldatum tachograaf", "Taxi indicator", "Maximum massa samenstelling", "Aantal rolstoelplaatsen",
+	"Maximum ondersteunende snelheid", "Jaar laatste registratie tellerstand", "Tellerstandoordeel",
+	"Code toelichting tellerstandoordeel", "Tenaamstellen mogelijk", "Vervaldatum APK DT", "Datum tenaamstelling DT",
+	"Datum eerste toelating DT", "Datum eerste tenaamstelling in Nederland DT", "Vervaldatum tachograaf DT",
+	"Maximum last onder de vooras(sen) (tezamen)/koppeling", "Type remsysteem voertuig code",
+	"Rupsonderstelconfiguratiecode", "Wielbasis voertuig minimum", "Wielbasis voertuig maximum", "Lengte voertuig minimum",
+	"Lengte voertuig maximum", "Breedte voertuig minimum", "Breedte voertuig maximum", "Hoogte voertuig",
+	"Hoogte voertuig minimum", "Hoogte voertuig maximum", "Massa bedrijfsklaar minimaal", "Massa bedrijfsklaar maximaal",
+	"Technisch toelaatbaar massa koppelpunt", "Maximum massa technisch maximaal", "Maximum massa technisch minimaal",
+	"Subcategorie Nederland", "Verticale belasting koppelpunt getrokken voertuig", "Zuinigheidsclassificatie",
+	"Registratie datum goedkeuring (afschrijvingsmoment BPM)", "Registratie datum goedkeuring (afschrijvingsmoment BPM) DT",
+	"Gem. lading wrde", "Aerodyn. voorz.", "Massa alt. aandr.", "Verl. cab. ind.", "API Gekentekende_voertuigen_assen",
+	"API Gekentekende_voertuigen_brandstof", "API Gekentekende_voertuigen_carrosserie",
+	"API Gekentekende_voertuigen_carrosserie_specifiek", "API Gekentekende_voertuigen_voertuigklasse",
+}
+
+func TestNormalizeColumnName(t *testing.T) {
+	tests := map[string]string{
+		"vervaldatum_apk":  "vervaldatum_apk",
+		"Vervaldatum APK":  "vervaldatum_apk",
+		"  Merk ":          "merk",
+		"\ufeffKenteken":   "kenteken",
+		"Gem. lading wrde": "gem_lading_wrde",
+		"Maximum last onder de vooras(sen) (tezamen)/koppeling": "maximum_last_onder_de_vooras_sen_tezamen_koppeling",
+		"API Gekentekende_voertuigen_assen":                     "api_gekentekende_voertuigen_assen",
+	}
+	for header, want := range tests {
+		if got := normalizeColumnName(header); got != want {
+			t.Errorf("normalizeColumnName(%q) = %q, want %q", header, got, want)
+		}
+	}
+}
+
+func TestCSVColumnMapExportHeader(t *testing.T) {
+	columns, err := newCSVColumnMap(rdwExportHeader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, index := range columns.indices {
+		if index < 0 {
+			t.Errorf("column %s is not mapped", voertuigColumns[i].name)
+		}
+	}
+}
+
+func TestCSVColumnMapAPIHeader(t *testing.T) {
+	// the API export has the column names in another order
+	header := strings.Split(voertuigColumnList(), ", ")
+	header[0], header[2] = header[2], header[0]
+	columns, err := newCSVColumnMap(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	row := make([]string, len(header))
+	row[0], row[2] = "TESLA", "xx-123-b"
+	record, err := columns.record(row, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.Kenteken != "XX123B" || record.Merk != "TESLA" {
+		t.Errorf("got kenteken %q and merk %q", record.Kenteken, record.Merk)
+	}
+}
+
+func TestCSVColumnMapMissingRequired(t *testing.T) {
+	if _, err := newCSVColumnMap([]string{"kenteken", "merk"}); err == nil {
+		t.Error("expected an error for the missing voertuigsoort column")
+	}
+}
+
+// TestNumericFieldTypes checks the numeric fields against the columns of the
+// MySQL schema: a DECIMAL bound to a NullInt would lose the decimals and an
+// INT bound to a NullDecimal would be rounded by the database, so the hash of
+// the stored row would never match the imported one.
+func TestNumericFieldTypes(t *testing.T) {
+	schema, err := migrationFiles.ReadFile("migrations/mysql/0001_initial.up.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	types := map[string]string{}
+	for _, match := range regexp.MustCompile(`(?m)^\s+(\w+)\s+(\w+)`).FindAllStringSubmatch(string(schema), -1) {
+		types[match[1]] = match[2]
+	}
+
+	var record RDWRecord
+	for _, column := range voertuigColumns {
+		switch column.field(&record).(type) {
+		case *NullDecimal:
+			if types[column.name] != "DECIMAL" {
+				t.Errorf("%s is bound as NullDecimal but is a %s column", column.name, types[column.name])
+			}
+		case *NullInt:
+			if types[column.name] == "DECIMAL" {
+				t.Errorf("%s is bound as NullInt but is a DECIMAL column", column.name)
+			}
+		}
+	}
+}
@@ -1,72 +1,60 @@
 package main
 
 import (
-	"log"
+	"database/sql"
 	"strconv"
 	"time"
 )
 
-func parseDateRdwFormat(dateString string) time.Time {
-	date := time.Time{}
-	err := error(nil)
-	// set default value for vervaldatum_apk to 1-1-1970
-	date = time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC)
+// parseDateRdwFormat parses a yyyymmdd date, an empty string is NULL.
+func parseDateRdwFormat(dateString string) (NullDate, error) {
+	if dateString == "" {
+		return NullDate{}, nil
+	}
 
-	if dateString != "" {
-		date, err = time.Parse("20060102", dateString)
-		if err != nil {
-			log.Fatal("Error parsing date", err)
-		}
+	date, err := time.Parse("20060102", dateString)
+	if err != nil {
+		return NullDate{}, err
 	}
-	return date
+	return NullDate{sql.NullTime{Time: date, Valid: true}}, nil
 }
 
-func parseISO8601Date(dateString string) time.Time {
-	date := time.Time{}
-	err := error(nil)
-	// Set default value for date to 1-1-1970
-	date = time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC)
+// parseISO8601Date parses the timestamp format of the *_dt columns, an empty
+// string is NULL.
+func parseISO8601Date(dateString string) (NullDate, error) {
+	if dateString == "" {
+		return NullDate{}, nil
+	}
 
-	if dateString != "" {
-		date, err = time.Parse("2006-01-02T15:04:05.000", dateString)
-		if err != nil {
-			log.Fatal("Error parsing ISO 8601 date", err)
-		}
+	date, err := time.Parse("2006-01-02T15:04:05.000", dateString)
+	if err != nil {
+		return NullDate{}, err
 	}
-	return date
+	return NullDate{sql.NullTime{Time: date, Valid: true}}, nil
 }
 
-func stringToDecimal(s string) float32 {
+func stringToDecimal(s string) (NullDecimal, error) {
 	if s == "" {
-		return 0.00
+		return NullDecimal{}, nil
 	}
 
-	f, err := strconv.ParseFloat(s, 32)
+	f, err := strconv.ParseFloat(s, 64)
 	if err != nil {
-		log.Println("Error converting string to float", err)
+		return NullDecimal{}, err
 	}
 
-	return float32(f)
+	return NullDecimal{sql.NullFloat64{Float64: f, Valid: true}}, nil
 }
 
-func stringToInt(s string) int {
+func stringToInt(s string) (NullInt, error) {
 	if s == "" {
-		return 0
+		return NullInt{}, nil
 	}
 
-	i, err := strconv.Atoi(s)
+	i, err := strconv.ParseInt(s, 10, 64)
 	if err != nil {
-		log.Println(s)
-		log.Println("Error converting string to int", err)
+		return NullInt{}, err
 	}
 
-	return i
-}
-
-func stringToFloatToInt(s string) int {
-	f, err := strconv.ParseFloat(s, 64)
-	if err != nil {
-		log.Fatalf("Error converting string to float: %v", err)
-	}
-	return int(f)
+	return NullInt{sql.NullInt64{Int64: i, Valid: true}}, nil
 }
@@ -6,8 +6,14 @@ import (
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/joho/godotenv"
 	"log"
+	"strings"
 )
 
+// execer is implemented by both *sql.DB and *sql.Tx.
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
 var (
 	dbUser string
 	dbPass string
@@ -31,7 +37,7 @@ func init() {
 
 // opens a connection to the MySQL database
 func connectToDB() (*sql.DB, error) {
-	db, err := sql.Open("mysql", fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&collation=utf8mb4_unicode_ci", dbUser, dbPass, dbHost, dbPort, dbName))
+	db, err := sql.Open("mysql", fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&collation=utf8mb4_unicode_ci&parseTime=true", dbUser, dbPass, dbHost, dbPort, dbName))
 	if err != nil {
 		return nil, err
 	}
@@ -48,3 +54,67 @@ func connectToDB() (*sql.DB, error) {
 
 	return db, nil
 }
+
+// fetches the columns of a single vehicle by its kenteken, returns sql.ErrNoRows when it is not found
+func getVoertuig(db *sql.DB, kenteken string, columns projection) (RDWRecord, error) {
+	var record RDWRecord
+	row := db.QueryRow("SELECT "+columns.columnList()+" FROM voertuigen WHERE kenteken = ?", kenteken)
+	err := row.Scan(columns.scanDest(&record)...)
+	return record, err
+}
+
+// fetches the columns of the vehicles with the given kentekens, kentekens that are not found are absent from the map
+func getVoertuigen(db *sql.DB, kentekens []string, columns projection) (map[string]RDWRecord, error) {
+	return getTableVoertuigen(db, voertuigenTable, kentekens, columns)
+}
+
+// getVoertuigen on table, which is voertuigen or one of its staging or backup tables
+func getTableVoertuigen(db *sql.DB, table string, kentekens []string, columns projection) (map[string]RDWRecord, error) {
+	records := make(map[string]RDWRecord, len(kentekens))
+	if len(kentekens) == 0 {
+		return records, nil
+	}
+
+	columns = columns.with("kenteken")
+	rows, err := db.Query("SELECT "+columns.columnList()+" FROM "+table+" WHERE kenteken IN ("+placeholders(len(kentekens))+")", stringArgs(kentekens)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var record RDWRecord
+		if err := rows.Scan(columns.scanDest(&record)...); err != nil {
+			return nil, err
+		}
+		records[record.Kenteken] = record
+	}
+	return records, rows.Err()
+}
+
+// counts the vehicles, the removed ones and returns the newest snapshot date
+func getVehicleStats(db *sql.DB) (vehicleStats, error) {
+	var stats vehicleStats
+	err := db.QueryRow("SELECT COUNT(*), COUNT(removed_at), MAX(snapshot_date) FROM voertuigen").Scan(&stats.Total, &stats.Removed, &stats.LastSnapshot)
+	return stats, err
+}
+
+// insertRowsSQL returns an INSERT statement of rows rows into columns of
+// table, with the arguments of every row after each other.
+func insertRowsSQL(table string, columns []string, rows int) string {
+	row := "(" + placeholders(len(columns)) + ")"
+	return "INSERT INTO " + table + " (" + strings.Join(columns, ", ") + ") VALUES " + strings.TrimSuffix(strings.Repeat(row+", ", rows), ", ")
+}
+
+// returns n comma separated placeholders for an IN clause
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+func stringArgs(values []string) []any {
+	args := make([]any, len(values))
+	for i, value := range values {
+		args[i] = value
+	}
+	return args
+}
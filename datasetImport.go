@@ -0,0 +1,167 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"hash"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+// runDatasetImport imports one linked RDW dataset, e.g.
+// "import-dataset brandstof --from-url". The dataset is always fully replaced
+// through a staging table, like the swap mode of the voertuigen import, and
+// the run is recorded in import_runs under the name of the dataset.
+func runDatasetImport(args []string) {
+	if len(args) == 0 {
+		log.Fatalf("Usage: import-dataset <%s> [-file path] [--from-url]", strings.Join(datasetNames(), "|"))
+	}
+	dataset, ok := findDataset(allDatasets(), args[0])
+	if !ok {
+		log.Fatalf("Unknown dataset %q, expected one of: %s", args[0], strings.Join(datasetNames(), ", "))
+	}
+
+	flags := flag.NewFlagSet("import-dataset", flag.ExitOnError)
+	path := flags.String("file", "", "path of the dataset CSV to import")
+	fromURL := flags.Bool("from-url", false, "download the CSV from the RDW open data portal instead of reading a file")
+	rejectsPath := flags.String("rejects", dataset.name+"-"+defaultRejectsFile, "CSV file receiving the rows that could not be converted")
+	maxErrors := flags.Int("max-errors", defaultMaxErrors, "number of rejected rows after which the import is aborted")
+	flags.Parse(args[1:])
+
+	if *path == "" && !*fromURL {
+		log.Fatal("Either -file or --from-url is required")
+	}
+
+	defer timeTrack(time.Now(), "Importing "+dataset.name)
+
+	// like the voertuigen import, a file is hashed up front and a download
+	// while it is read
+	sourceName, size, checksum := rdwResourceURL(dataset.resource), NullInt{}, ""
+	var source io.ReadCloser
+	var input io.Reader
+	var download *rdwDownload
+	var downloaded hash.Hash
+	var err error
+	if *fromURL {
+		log.Printf("Downloading %s", sourceName)
+		if download, err = openRDWDownload(sourceName); err != nil {
+			log.Fatal("Error opening CSV source ", err)
+		}
+		downloaded = sha256.New()
+		source, input = download, io.TeeReader(download, downloaded)
+	} else {
+		sourceName = *path
+		file, err := os.Open(sourceName)
+		if err != nil {
+			log.Fatal("Error opening CSV source ", err)
+		}
+		info, err := file.Stat()
+		if err != nil {
+			log.Fatal("Error reading the size of the CSV ", err)
+		}
+		size.Int64, size.Valid = info.Size(), true
+		if checksum, err = fileSHA256(sourceName); err != nil {
+			log.Fatal("Error computing the checksum of the CSV ", err)
+		}
+		source, input = file, file
+	}
+	defer source.Close()
+
+	store, err := openStore()
+	if err != nil {
+		log.Fatal("Error connecting to the database ", err)
+	}
+
+	stats := &importStats{}
+	today, _ := time.Parse(time.DateOnly, time.Now().Format(time.DateOnly))
+	run, err := startImportRun(store, dataset.name, sourceName, size, checksum, today, "swap", stats)
+	if err != nil {
+		log.Fatal("Error recording the import run ", err)
+	}
+
+	if err := importDataset(store, dataset, input, *rejectsPath, *maxErrors, run); err != nil {
+		run.fatal(err)
+	}
+	if download != nil {
+		run.hash = hex.EncodeToString(downloaded.Sum(nil))
+		run.size.Int64, run.size.Valid = download.offset, true
+	}
+	run.finish("succeeded", "")
+	log.Printf("Imported %s: read %d records, %d rejected, %d failed to insert", dataset.name, stats.read.Load(), stats.rejected.Load(), stats.failed.Load())
+}
+
+// importDataset loads the CSV into the staging copy of the dataset table and
+// swaps it into place once it passes validation, counting the rows in the
+// stats of run. The rows and the staging table are written by store.
+func importDataset(store importStore, dataset rdwDataset, source io.Reader, rejectsPath string, maxErrors int, run *runRecorder) error {
+	reader := csv.NewReader(source)
+	header, err := reader.Read()
+	if err != nil {
+		return fmt.Errorf("error reading the header line: %w", err)
+	}
+	indices, err := mapCSVHeader(header, dataset.columnNames(), dataset.key)
+	if err != nil {
+		return fmt.Errorf("error mapping the header line: %w", err)
+	}
+	reader.FieldsPerRecord = len(header)
+
+	rejects := newRejectsWriter(rejectsPath, header)
+	defer rejects.Close()
+
+	staging, err := store.CreateStaging(dataset.table)
+	if err != nil {
+		return fmt.Errorf("error creating staging table: %w", err)
+	}
+
+	stats := run.stats
+	batches := &csvBatches[[]any]{
+		reader: reader,
+		convert: func(values []string, line int) ([]any, error) {
+			return dataset.row(values, indices, line)
+		},
+		rejects:     rejects,
+		rejectsPath: rejectsPath,
+		maxErrors:   maxErrors,
+		stats:       stats,
+	}
+	writer := newBatchWriter()
+	err = batches.run(func(batch [][]any) {
+		writer.write(func() {
+			failed, err := store.WriteRows(staging, dataset.columnNames(), batch)
+			if err != nil {
+				run.fatal("Error writing rows ", err)
+			}
+			stats.failed.Add(failed)
+		})
+	})
+	if err != nil {
+		return err
+	}
+	writer.wait()
+
+	written := stats.read.Load() - stats.rejected.Load() - stats.failed.Load()
+	if err := store.ValidateStaging(dataset.table, staging, written, dataset.key[:1]); err != nil {
+		return fmt.Errorf("staging table failed validation, keeping the current table: %w", err)
+	}
+	backup, err := store.SwapStaging(dataset.table, staging)
+	if err != nil {
+		return fmt.Errorf("error swapping staging table into place: %w", err)
+	}
+	stats.inserted.Store(written)
+	log.Printf("Swapped %s into place, previous table kept as %s", staging, backup)
+	return nil
+}
+
+func datasetNames() []string {
+	var names []string
+	for _, dataset := range allDatasets() {
+		names = append(names, dataset.name)
+	}
+	return names
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/FayKn/RDW-Kenteken-Api/kenteken"
+)
+
+// columnKind tells how a CSV value of a dataset column is converted.
+type columnKind int
+
+const (
+	textColumn columnKind = iota
+	longTextColumn
+	intColumn
+	decimalColumn
+	dateColumn
+)
+
+// datasetColumn is a column of a linked RDW dataset, named like the column in
+// the RDW CSV and in the database table.
+type datasetColumn struct {
+	name string
+	kind columnKind
+}
+
+// rdwDataset describes an RDW open data dataset that is imported into its own
+// table next to voertuigen. The key columns form the primary key of the table.
+type rdwDataset struct {
+	name     string
+	resource string
+	table    string
+	key      []string
+	columns  []datasetColumn
+}
+
+// linkedDatasets are the datasets the api_gekentekende_voertuigen_* columns of
+// voertuigen point at. Their tables are defined in the migrations.
+var linkedDatasets = []rdwDataset{
+	{
+		name:     "assen",
+		resource: "3huj-srit",
+		table:    "voertuigen_assen",
+		key:      []string{"kenteken", "as_nummer"},
+		columns: []datasetColumn{
+			{"kenteken", textColumn},
+			{"as_nummer", intColumn},
+			{"aantal_assen", intColumn},
+			{"aangedreven_as", textColumn},
+			{"hefas", textColumn},
+			{"plaatscode_as", textColumn},
+			{"spoorbreedte", intColumn},
+			{"weggedrag_code", textColumn},
+			{"wettelijk_toegestane_maximum_aslast", intColumn},
+			{"technisch_toegestane_maximum_aslast", intColumn},
+			{"afstand_tot_volgende_as_voertuig", intColumn},
+			{"afstand_tot_volgende_as_voertuig_minimum", intColumn},
+			{"afstand_tot_volgende_as_voertuig_maximum", intColumn},
+			{"maximum_last_as_technisch_maximaal", intColumn},
+			{"maximum_last_as_technisch_minimaal", intColumn},
+		},
+	},
+	{
+		name:     "brandstof",
+		resource: "8ys7-d773",
+		table:    "voertuigen_brandstof",
+		key:      []string{"kenteken", "brandstof_volgnummer"},
+		columns: []datasetColumn{
+			{"kenteken", textColumn},
+			{"brandstof_volgnummer", intColumn},
+			{"brandstof_omschrijving", textColumn},
+			{"brandstofverbruik_buiten", decimalColumn},
+			{"brandstofverbruik_gecombineerd", decimalColumn},
+			{"brandstofverbruik_stad", decimalColumn},
+			{"co2_uitstoot_gecombineerd", decimalColumn},
+			{"co2_uitstoot_gewogen", decimalColumn},
+			{"geluidsniveau_rijdend", decimalColumn},
+			{"geluidsniveau_stationair", decimalColumn},
+			{"emissiecode_omschrijving", textColumn},
+			{"milieuklasse_eg_goedkeuring_licht", textColumn},
+			{"milieuklasse_eg_goedkeuring_zwaar", textColumn},
+			{"uitstoot_deeltjes_licht", decimalColumn},
+			{"uitstoot_deeltjes_zwaar", decimalColumn},
+			{"nettomaximumvermogen", decimalColumn},
+			{"nominaal_continu_maximumvermogen", decimalColumn},
+			{"roetuitstoot", decimalColumn},
+			{"toerental_geluidsniveau", decimalColumn},
+			{"emis_deeltjes_type1_wltp", decimalColumn},
+			{"emissie_co2_gecombineerd_wltp", decimalColumn},
+			{"emis_co2_gewogen_gecombineerd_wltp", decimalColumn},
+			{"brandstof_verbruik_gecombineerd_wltp", decimalColumn},
+			{"brandstof_verbruik_gewogen_gecombineerd_wltp", decimalColumn},
+			{"elektrisch_verbruik_enkel_elektrisch_wltp", decimalColumn},
+			{"actie_radius_enkel_elektrisch_wltp", decimalColumn},
+			{"actie_radius_enkel_elektrisch_stad_wltp", decimalColumn},
+			{"elektrisch_verbruik_extern_opladen_wltp", decimalColumn},
+			{"actie_radius_extern_opladen_wltp", decimalColumn},
+			{"actie_radius_extern_opladen_stad_wltp", decimalColumn},
+			{"max_vermogen_15_minuten", decimalColumn},
+			{"max_vermogen_60_minuten", decimalColumn},
+			{"netto_max_vermogen_elektrisch", decimalColumn},
+			{"klasse_hybride_elektrisch_voertuig", textColumn},
+			{"opgegeven_maximum_snelheid", decimalColumn},
+			{"uitlaatemissieniveau", textColumn},
+		},
+	},
+	{
+		name:     "carrosserie",
+		resource: "vezc-m2t6",
+		table:    "voertuigen_carrosserie",
+		key:      []string{"kenteken", "carrosserie_volgnummer"},
+		columns: []datasetColumn{
+			{"kenteken", textColumn},
+			{"carrosserie_volgnummer", intColumn},
+			{"carrosserietype", textColumn},
+			{"type_carrosserie_europese_omschrijving", textColumn},
+		},
+	},
+	{
+		name:     "carrosserie_specifiek",
+		resource: "jhie-znh9",
+		table:    "voertuigen_carrosserie_specifiek",
+		key:      []string{"kenteken", "carrosserie_volgnummer", "carrosserie_voertuig_nummer_code_volgnummer"},
+		columns: []datasetColumn{
+			{"kenteken", textColumn},
+			{"carrosserie_volgnummer", intColumn},
+			{"carrosserie_voertuig_nummer_code_volgnummer", intColumn},
+			{"carrosseriecode", textColumn},
+			{"carrosserie_voertuig_nummer_europese_omschrijving", textColumn},
+		},
+	},
+	{
+		name:     "voertuigklasse",
+		resource: "kmfi-hrps",
+		table:    "voertuigen_voertuigklasse",
+		key:      []string{"kenteken", "carrosserie_volgnummer", "carrosserie_klasse_volgnummer"},
+		columns: []datasetColumn{
+			{"kenteken", textColumn},
+			{"carrosserie_volgnummer", intColumn},
+			{"carrosserie_klasse_volgnummer", intColumn},
+			{"voertuigklasse", textColumn},
+			{"voertuigklasse_omschrijving", textColumn},
+		},
+	},
+}
+
+// allDatasets returns every dataset import-dataset can import.
+func allDatasets() []rdwDataset {
+	return slices.Concat(linkedDatasets, recallDatasets, apkDatasets)
+}
+
+// findDataset returns the dataset with the given name from datasets.
+func findDataset(datasets []rdwDataset, name string) (rdwDataset, bool) {
+	for _, dataset := range datasets {
+		if dataset.name == name {
+			return dataset, true
+		}
+	}
+	return rdwDataset{}, false
+}
+
+func (d rdwDataset) columnNames() []string {
+	names := make([]string, len(d.columns))
+	for i, column := range d.columns {
+		names[i] = column.name
+	}
+	return names
+}
+
+// row converts the CSV values mapped by indices into statement arguments.
+// Empty values become NULL and kentekens are normalized.
+func (d rdwDataset) row(values []string, indices []int, line int) ([]any, error) {
+	row := make([]any, len(d.columns))
+	var errs []error
+	for i, column := range d.columns {
+		value := ""
+		if indices[i] >= 0 && indices[i] < len(values) {
+			value = values[indices[i]]
+		}
+
+		var err error
+		switch column.kind {
+		case textColumn, longTextColumn:
+			if column.name == "kenteken" {
+				value = kenteken.Normalize(value)
+			}
+			row[i] = sql.NullString{String: value, Valid: value != ""}
+		case intColumn:
+			row[i], err = stringToInt(value)
+		case decimalColumn:
+			row[i], err = stringToDecimal(value)
+		case dateColumn:
+			row[i], err = parseDateRdwFormat(value)
+		}
+		if err != nil {
+			errs = append(errs, &rowError{Line: line, Column: column.name, Value: value, Err: err})
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return row, nil
+}
+
+// scanDest returns typed Scan destinations for every column.
+func (d rdwDataset) scanDest() []any {
+	dest := make([]any, len(d.columns))
+	for i, column := range d.columns {
+		switch column.kind {
+		case textColumn, longTextColumn:
+			dest[i] = new(sql.NullString)
+		case intColumn:
+			dest[i] = new(NullInt)
+		case decimalColumn:
+			dest[i] = new(NullDecimal)
+		case dateColumn:
+			dest[i] = new(NullDate)
+		}
+	}
+	return dest
+}
+
+// fetches the rows of a dataset where column equals value as JSON ready objects, ordered by the key
+func getDatasetRows(db *sql.DB, dataset rdwDataset, column, value string) ([]map[string]any, error) {
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE %s = ? ORDER BY %s",
+		strings.Join(dataset.columnNames(), ", "), dataset.table, column, strings.Join(dataset.key, ", "))
+	rows, err := db.Query(query, value)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []map[string]any{}
+	for rows.Next() {
+		dest := dataset.scanDest()
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+
+		object := make(map[string]any, len(dest))
+		for i, column := range dataset.columns {
+			if text, ok := dest[i].(*sql.NullString); ok {
+				if text.Valid {
+					object[column.name] = text.String
+				} else {
+					object[column.name] = nil
+				}
+				continue
+			}
+			object[column.name] = dest[i]
+		}
+		result = append(result, object)
+	}
+	return result, rows.Err()
+}
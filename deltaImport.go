@@ -0,0 +1,217 @@
+package main
+
+import (
+	"database/sql"
+	"log"
+	"strings"
+	"time"
+)
+
+// storedVersion is what the delta import needs to know about a stored vehicle.
+type storedVersion struct {
+	hash     string
+	removed  bool
+	snapshot NullDate
+}
+
+// writeDelta compares one batch against the stored vehicles by their row
+// hash. Added and changed vehicles are written with the upsert query and
+// recorded in voertuigen_changes and voertuigen_history, unchanged vehicles
+// only get their snapshot date bumped so markRemoved knows they are still
+// registered. Every row is written within a savepoint, so a row that fails
+// is left out as a whole and the rest of the batch is still committed.
+func writeDelta(db *sql.DB, upsertQuery string, records []RDWRecord, snapshot time.Time) (writeResult, error) {
+	var result writeResult
+
+	kentekens := make([]string, len(records))
+	hashes := make([]string, len(records))
+	for i := range records {
+		kentekens[i] = records[i].Kenteken
+		hashes[i] = records[i].hash()
+	}
+	stored, err := getStoredVersions(db, voertuigenTable, kentekens)
+	if err != nil {
+		return result, err
+	}
+
+	var changedKentekens []string
+	for i := range records {
+		version, ok := stored[records[i].Kenteken]
+		if ok && !version.removed && version.hash != hashes[i] {
+			changedKentekens = append(changedKentekens, records[i].Kenteken)
+		}
+	}
+	previous, err := getVoertuigen(db, changedKentekens, voertuigColumns)
+	if err != nil {
+		return result, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return result, err
+	}
+	defer tx.Rollback()
+	upsertStmt, err := tx.Prepare(upsertQuery)
+	if err != nil {
+		return result, err
+	}
+	defer upsertStmt.Close()
+	changeStmt, err := tx.Prepare("INSERT INTO voertuigen_changes (kenteken, change_type, changed_columns, snapshot_date) VALUES (?, ?, ?, ?)")
+	if err != nil {
+		return result, err
+	}
+	defer changeStmt.Close()
+	history, err := prepareHistoryStatements(tx)
+	if err != nil {
+		return result, err
+	}
+	defer history.Close()
+
+	var unchanged []string
+	for i := range records {
+		record := &records[i]
+		version, ok := stored[record.Kenteken]
+
+		changeType := "added"
+		var changedColumns sql.NullString
+		var old RDWRecord
+		if ok && !version.removed {
+			var found bool
+			old, found = previous[record.Kenteken]
+			if version.hash == hashes[i] || !found {
+				unchanged = append(unchanged, record.Kenteken)
+				continue
+			}
+			columns := record.changedColumns(&old)
+			if len(columns) == 0 {
+				unchanged = append(unchanged, record.Kenteken)
+				continue
+			}
+			changeType = "changed"
+			changedColumns = sql.NullString{String: strings.Join(columns, ","), Valid: true}
+		}
+
+		// a row that fails is rolled back to its savepoint: PostgreSQL refuses
+		// every further statement of a transaction after an error
+		if _, err := tx.Exec("SAVEPOINT delta_row"); err != nil {
+			return result, err
+		}
+		err := func() error {
+			if _, err := upsertStmt.Exec(voertuigArgs(record, snapshot)...); err != nil {
+				log.Println("Error upserting record ", err)
+				return err
+			}
+			if _, err := changeStmt.Exec(record.Kenteken, changeType, changedColumns, snapshot); err != nil {
+				log.Println("Error recording change ", err)
+				return err
+			}
+			var err error
+			if changeType == "changed" {
+				err = history.change(record, &old, hashes[i], version, changedColumns, snapshot)
+			} else {
+				err = history.add(record, hashes[i], snapshot)
+			}
+			if err != nil {
+				log.Println("Error recording history ", err)
+			}
+			return err
+		}()
+		if err != nil {
+			result.failed++
+			if _, err := tx.Exec("ROLLBACK TO SAVEPOINT delta_row"); err != nil {
+				return result, err
+			}
+			continue
+		}
+		if _, err := tx.Exec("RELEASE SAVEPOINT delta_row"); err != nil {
+			return result, err
+		}
+		if changeType == "added" {
+			result.inserted++
+		} else {
+			result.updated++
+		}
+	}
+
+	if err := touchSnapshot(tx, voertuigenTable, snapshot, unchanged); err != nil {
+		return result, err
+	}
+	result.unchanged = int64(len(unchanged))
+
+	return result, tx.Commit()
+}
+
+// markRemoved marks every vehicle that was not part of the snapshot as
+// removed, records that in voertuigen_changes and closes its history version.
+// Vehicles are never deleted.
+func markRemoved(db *sql.DB, snapshot time.Time, seen []string) (int64, error) {
+	if err := touchSnapshot(db, voertuigenTable, snapshot, seen); err != nil {
+		return 0, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	const notSeen = "removed_at IS NULL AND (snapshot_date IS NULL OR snapshot_date < ?)"
+	_, err = tx.Exec("INSERT INTO voertuigen_changes (kenteken, change_type, snapshot_date) SELECT kenteken, 'removed', ? FROM voertuigen WHERE "+notSeen, snapshot, snapshot)
+	if err != nil {
+		return 0, err
+	}
+	_, err = tx.Exec("UPDATE voertuigen_history SET valid_to = ? WHERE valid_to IS NULL AND kenteken IN (SELECT kenteken FROM voertuigen WHERE "+notSeen+")", snapshot, snapshot, snapshot)
+	if err != nil {
+		return 0, err
+	}
+	result, err := tx.Exec("UPDATE voertuigen SET removed_at = ? WHERE "+notSeen, snapshot, snapshot)
+	if err != nil {
+		return 0, err
+	}
+	removed, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return removed, tx.Commit()
+}
+
+// getStoredVersions returns the row hash and removed state of the given
+// kentekens that are already stored in table.
+func getStoredVersions(db *sql.DB, table string, kentekens []string) (map[string]storedVersion, error) {
+	versions := make(map[string]storedVersion, len(kentekens))
+	if len(kentekens) == 0 {
+		return versions, nil
+	}
+
+	rows, err := db.Query("SELECT kenteken, row_hash, removed_at IS NOT NULL, snapshot_date FROM "+table+" WHERE kenteken IN ("+placeholders(len(kentekens))+")", stringArgs(kentekens)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var kenteken string
+		var hash sql.NullString
+		var version storedVersion
+		if err := rows.Scan(&kenteken, &hash, &version.removed, &version.snapshot); err != nil {
+			return nil, err
+		}
+		version.hash = hash.String
+		versions[kenteken] = version
+	}
+	return versions, rows.Err()
+}
+
+// touchSnapshot sets the snapshot date of vehicles in table that are still
+// registered but did not need to be rewritten.
+func touchSnapshot(db execer, table string, snapshot time.Time, kentekens []string) error {
+	const chunkSize = 1000
+	for start := 0; start < len(kentekens); start += chunkSize {
+		chunk := kentekens[start:min(start+chunkSize, len(kentekens))]
+		args := append([]any{snapshot}, stringArgs(chunk)...)
+		if _, err := db.Exec("UPDATE "+table+" SET snapshot_date = ? WHERE kenteken IN ("+placeholders(len(chunk))+")", args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,335 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// historyVersion is one entry of a vehicle's timeline: the period a version
+// was valid and the columns that changed compared to the version before it.
+type historyVersion struct {
+	ValidFrom NullDate                 `json:"valid_from"`
+	ValidTo   NullDate                 `json:"valid_to"`
+	Changes   map[string]historyChange `json:"changes"`
+}
+
+// historyChange holds the old and new value of a changed column.
+type historyChange struct {
+	Old json.RawMessage `json:"old"`
+	New json.RawMessage `json:"new"`
+}
+
+// historyStatements writes voertuigen_history within an import transaction.
+// Every version stores the full record as JSON, valid from the snapshot it
+// was first seen in until the snapshot that replaced it (NULL while current).
+type historyStatements struct {
+	close  *sql.Stmt
+	insert *sql.Stmt
+}
+
+func prepareHistoryStatements(tx *sql.Tx) (*historyStatements, error) {
+	closeStmt, err := tx.Prepare("UPDATE voertuigen_history SET valid_to = ? WHERE kenteken = ? AND valid_to IS NULL")
+	if err != nil {
+		return nil, err
+	}
+	insertStmt, err := tx.Prepare("INSERT INTO voertuigen_history (kenteken, valid_from, valid_to, row_hash, changed_columns, record) VALUES (?, ?, ?, ?, ?, ?)")
+	if err != nil {
+		closeStmt.Close()
+		return nil, err
+	}
+	return &historyStatements{close: closeStmt, insert: insertStmt}, nil
+}
+
+func (h *historyStatements) Close() {
+	h.close.Close()
+	h.insert.Close()
+}
+
+// add opens the first version of a new or re-registered vehicle.
+func (h *historyStatements) add(record *RDWRecord, hash string, snapshot time.Time) error {
+	if _, err := h.close.Exec(snapshot, record.Kenteken); err != nil {
+		return err
+	}
+	return h.insertVersion(record, hash, snapshot, nil, sql.NullString{})
+}
+
+// change closes the open version and opens a new one. A vehicle imported
+// before the history existed has no open version; its previous state is
+// recorded first, valid from its stored snapshot date.
+func (h *historyStatements) change(record, previous *RDWRecord, hash string, stored storedVersion, changedColumns sql.NullString, snapshot time.Time) error {
+	result, err := h.close.Exec(snapshot, record.Kenteken)
+	if err != nil {
+		return err
+	}
+	closed, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if closed == 0 {
+		if err := h.insertVersion(previous, stored.hash, stored.snapshot, snapshot, sql.NullString{}); err != nil {
+			return err
+		}
+	}
+	return h.insertVersion(record, hash, snapshot, nil, changedColumns)
+}
+
+func (h *historyStatements) insertVersion(record *RDWRecord, hash string, validFrom, validTo any, changedColumns sql.NullString) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	_, err = h.insert.Exec(record.Kenteken, validFrom, validTo, hash, changedColumns, data)
+	return err
+}
+
+// hasVoertuigHistory reports whether voertuigen_history has any version. The
+// delta import starts the history; until then the other import modes and a
+// rollback have nothing to keep in step.
+func hasVoertuigHistory(db *sql.DB) (bool, error) {
+	var versions int
+	err := db.QueryRow("SELECT COUNT(*) FROM (SELECT 1 FROM voertuigen_history LIMIT 1) AS versions").Scan(&versions)
+	return versions > 0, err
+}
+
+// writeWithHistory runs write for a batch of an insert or upsert into the
+// live voertuigen and records the vehicles it added or changed in
+// voertuigen_history, like writeDelta does. Which rows were written is read
+// back from their row hashes, so rows that failed, like a duplicate kenteken
+// in insert mode, get no version. Other tables and modes, and a database
+// without history, only run write.
+func writeWithHistory(db *sql.DB, table, mode string, records []RDWRecord, snapshot time.Time, write func() (writeResult, error)) (writeResult, error) {
+	if table != voertuigenTable || mode == "delta" {
+		return write()
+	}
+	history, err := hasVoertuigHistory(db)
+	if err != nil {
+		return writeResult{}, err
+	}
+	if !history {
+		return write()
+	}
+
+	kentekens := make([]string, len(records))
+	for i := range records {
+		kentekens[i] = records[i].Kenteken
+	}
+	before, err := getStoredVersions(db, voertuigenTable, kentekens)
+	if err != nil {
+		return writeResult{}, err
+	}
+	var changedKentekens []string
+	for i := range records {
+		version, ok := before[records[i].Kenteken]
+		if ok && !version.removed && version.hash != records[i].hash() {
+			changedKentekens = append(changedKentekens, records[i].Kenteken)
+		}
+	}
+	previous, err := getVoertuigen(db, changedKentekens, voertuigColumns)
+	if err != nil {
+		return writeResult{}, err
+	}
+
+	result, err := write()
+	if err != nil {
+		return result, err
+	}
+	after, err := getStoredVersions(db, voertuigenTable, kentekens)
+	if err != nil {
+		return result, err
+	}
+
+	return result, inHistoryTx(db, func(history *historyStatements) error {
+		for i := range records {
+			record := &records[i]
+			hash := record.hash()
+			if after[record.Kenteken].hash != hash {
+				continue
+			}
+			version, ok := before[record.Kenteken]
+			switch {
+			case !ok || version.removed:
+				if err := history.add(record, hash, snapshot); err != nil {
+					return err
+				}
+			case version.hash != hash:
+				old, found := previous[record.Kenteken]
+				columns := record.changedColumns(&old)
+				if !found || len(columns) == 0 {
+					break
+				}
+				changedColumns := sql.NullString{String: strings.Join(columns, ","), Valid: true}
+				if err := history.change(record, &old, hash, version, changedColumns, snapshot); err != nil {
+					return err
+				}
+			}
+			// a kenteken that occurs twice in the batch is recorded once
+			before[record.Kenteken] = storedVersion{hash: hash, snapshot: version.snapshot}
+		}
+		return nil
+	})
+}
+
+// recordReplacedTable brings voertuigen_history in step after a swap or a
+// rollback replaced voertuigen with another table, kept as previous: vehicles
+// that are new or registered again open a version, changed vehicles close
+// theirs and open a new one, and vehicles that are gone or removed close
+// theirs, all on date.
+func recordReplacedTable(db *sql.DB, previous string, date time.Time) error {
+	history, err := hasVoertuigHistory(db)
+	if err != nil || !history {
+		return err
+	}
+
+	_, err = db.Exec("UPDATE voertuigen_history SET valid_to = ? WHERE valid_to IS NULL AND NOT EXISTS"+
+		" (SELECT 1 FROM voertuigen WHERE voertuigen.kenteken = voertuigen_history.kenteken AND voertuigen.removed_at IS NULL)", date)
+	if err != nil {
+		return err
+	}
+
+	added, err := queryKentekens(db, "SELECT v.kenteken FROM voertuigen v LEFT JOIN "+previous+" p ON p.kenteken = v.kenteken AND p.removed_at IS NULL"+
+		" WHERE v.removed_at IS NULL AND p.kenteken IS NULL")
+	if err != nil {
+		return err
+	}
+	changed, err := queryKentekens(db, "SELECT v.kenteken FROM voertuigen v JOIN "+previous+" p ON p.kenteken = v.kenteken"+
+		" WHERE v.removed_at IS NULL AND p.removed_at IS NULL AND (p.row_hash IS NULL OR p.row_hash <> v.row_hash)")
+	if err != nil {
+		return err
+	}
+
+	const chunkSize = 1000
+	for start := 0; start < len(added); start += chunkSize {
+		records, err := getVoertuigen(db, added[start:min(start+chunkSize, len(added))], voertuigColumns)
+		if err != nil {
+			return err
+		}
+		err = inHistoryTx(db, func(history *historyStatements) error {
+			for _, record := range records {
+				if err := history.add(&record, record.hash(), date); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	for start := 0; start < len(changed); start += chunkSize {
+		chunk := changed[start:min(start+chunkSize, len(changed))]
+		records, err := getVoertuigen(db, chunk, voertuigColumns)
+		if err != nil {
+			return err
+		}
+		previousRecords, err := getTableVoertuigen(db, previous, chunk, voertuigColumns)
+		if err != nil {
+			return err
+		}
+		stored, err := getStoredVersions(db, previous, chunk)
+		if err != nil {
+			return err
+		}
+		err = inHistoryTx(db, func(history *historyStatements) error {
+			for kenteken, record := range records {
+				old := previousRecords[kenteken]
+				columns := record.changedColumns(&old)
+				if len(columns) == 0 {
+					continue
+				}
+				changedColumns := sql.NullString{String: strings.Join(columns, ","), Valid: true}
+				if err := history.change(&record, &old, record.hash(), stored[kenteken], changedColumns, date); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lastSnapshotDate returns the newest snapshot date in voertuigen, the date a
+// swapped in table was imported for.
+func lastSnapshotDate(db *sql.DB) (time.Time, error) {
+	var snapshot NullDate
+	err := db.QueryRow("SELECT MAX(snapshot_date) FROM voertuigen").Scan(&snapshot)
+	return snapshot.Time, err
+}
+
+// inHistoryTx runs f with the history statements of a new transaction and
+// commits it when f succeeds.
+func inHistoryTx(db *sql.DB, f func(history *historyStatements) error) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	history, err := prepareHistoryStatements(tx)
+	if err != nil {
+		return err
+	}
+	defer history.Close()
+
+	if err := f(history); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// queryKentekens returns the kentekens query selects.
+func queryKentekens(db *sql.DB, query string) ([]string, error) {
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var kentekens []string
+	for rows.Next() {
+		var kenteken string
+		if err := rows.Scan(&kenteken); err != nil {
+			return nil, err
+		}
+		kentekens = append(kentekens, kenteken)
+	}
+	return kentekens, rows.Err()
+}
+
+// fetches the timeline of a vehicle, oldest version first
+func getVoertuigHistory(db *sql.DB, kenteken string) ([]historyVersion, error) {
+	rows, err := db.Query("SELECT valid_from, valid_to, changed_columns, record FROM voertuigen_history WHERE kenteken = ? ORDER BY id", kenteken)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	versions := []historyVersion{}
+	var previous map[string]json.RawMessage
+	for rows.Next() {
+		var version historyVersion
+		var changedColumns sql.NullString
+		var data []byte
+		if err := rows.Scan(&version.ValidFrom, &version.ValidTo, &changedColumns, &data); err != nil {
+			return nil, err
+		}
+
+		var record map[string]json.RawMessage
+		if err := json.Unmarshal(data, &record); err != nil {
+			return nil, err
+		}
+		if changedColumns.Valid && previous != nil {
+			version.Changes = make(map[string]historyChange)
+			for _, column := range strings.Split(changedColumns.String, ",") {
+				version.Changes[column] = historyChange{Old: previous[column], New: record[column]}
+			}
+		}
+
+		versions = append(versions, version)
+		previous = record
+	}
+	return versions, rows.Err()
+}
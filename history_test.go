@@ -0,0 +1,84 @@
+package main
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// TestHistoryOtherModes starts the history with a delta import of the first
+// snapshot and checks that a swap, a rollback and an upsert keep it in step.
+func TestHistoryOtherModes(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("DB_DRIVER", "sqlite")
+	t.Setenv("SQLITE_FILE", filepath.Join(dir, "kentekens.db"))
+	importFile := func(file, snapshot, mode string) {
+		runImport([]string{
+			"-file", filepath.Join("testdata", "voertuigen-"+file+".csv"),
+			"-mode", mode,
+			"-snapshot-date", snapshot,
+			"-rejects", filepath.Join(dir, "rejects.csv"),
+		})
+	}
+
+	store, err := openStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	// versions returns the valid_from and valid_to of every version of
+	// kenteken, "" while it is open
+	versions := func(kenteken string) []string {
+		t.Helper()
+		history, err := store.History(kenteken)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var periods []string
+		for _, version := range history {
+			to := ""
+			if version.ValidTo.Valid {
+				to = version.ValidTo.Time.Format(time.DateOnly)
+			}
+			periods = append(periods, version.ValidFrom.Time.Format(time.DateOnly)+"/"+to)
+		}
+		return periods
+	}
+	check := func(step, kenteken string, want ...string) {
+		t.Helper()
+		if got := versions(kenteken); !slices.Equal(got, want) {
+			t.Errorf("after the %s %s has versions %q, want %q", step, kenteken, got, want)
+		}
+	}
+
+	importFile("2026-01-01", "2026-01-01", "delta")
+	importFile("2026-02-01", "2026-02-01", "swap")
+	check("swap", "XX123B", "2026-01-01/2026-02-01", "2026-02-01/")
+	check("swap", "GB123D", "2026-01-01/")
+	check("swap", "1TTT23", "2026-01-01/2026-02-01")
+	check("swap", "99XXX9", "2026-02-01/")
+	history, err := store.History("XX123B")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if change := history[1].Changes["eerste_kleur"]; string(change.Old) != `"ROOD"` || string(change.New) != `"BLAUW"` {
+		t.Errorf("the swap recorded the changes %v", history[1].Changes)
+	}
+
+	// the first snapshot again: XX123B is red again and 1TTT23 is back, the
+	// upsert leaves 99XXX9 alone
+	importFile("2026-01-01", "2026-03-01", "upsert")
+	check("upsert", "XX123B", "2026-01-01/2026-02-01", "2026-02-01/2026-03-01", "2026-03-01/")
+	check("upsert", "1TTT23", "2026-01-01/2026-02-01", "2026-03-01/")
+	check("upsert", "99XXX9", "2026-02-01/")
+
+	// back to the table the swap replaced, which has no 99XXX9
+	runRollback(nil)
+	today := time.Now().Format(time.DateOnly)
+	check("rollback", "XX123B", "2026-01-01/2026-02-01", "2026-02-01/2026-03-01", "2026-03-01/")
+	check("rollback", "1TTT23", "2026-01-01/2026-02-01", "2026-03-01/")
+	check("rollback", "99XXX9", "2026-02-01/"+today)
+	check("rollback", "GB123D", "2026-01-01/")
+}
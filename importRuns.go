@@ -0,0 +1,203 @@
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+const defaultRunsLimit = 20
+
+// importRun is a row of import_runs: one run of the voertuigen import or of
+// import-dataset. A run that is still "running" after the import ended was
+// killed before it could record its outcome.
+type importRun struct {
+	ID           int64      `json:"id"`
+	Dataset      string     `json:"dataset"`
+	StartedAt    time.Time  `json:"started_at"`
+	FinishedAt   *time.Time `json:"finished_at"`
+	Source       string     `json:"source"`
+	SourceSize   NullInt    `json:"source_size"`
+	SourceSHA256 *string    `json:"source_sha256"`
+	SnapshotDate NullDate   `json:"snapshot_date"`
+	Mode         string     `json:"mode"`
+	Read         int64      `json:"read"`
+	Inserted     int64      `json:"inserted"`
+	Updated      int64      `json:"updated"`
+	Unchanged    int64      `json:"unchanged"`
+	Rejected     int64      `json:"rejected"`
+	Failed       int64      `json:"failed"`
+	Outcome      string     `json:"outcome"`
+	Error        *string    `json:"error"`
+}
+
+// runRecorder keeps the import_runs row of the running import up to date.
+type runRecorder struct {
+	store importStore
+	id    int64
+	stats *importStats
+
+	// size and hash of the source, filled in at the end when the source is
+	// a download that is hashed while it is read
+	size NullInt
+	hash string
+
+	failed sync.Once
+}
+
+// startImportRun records the start of an import of source into dataset,
+// voertuigen or one of the linked datasets.
+func startImportRun(store importStore, dataset, source string, size NullInt, hash string, snapshot time.Time, mode string, stats *importStats) (*runRecorder, error) {
+	run := &runRecorder{store: store, stats: stats, size: size, hash: hash}
+	id, err := store.StartImportRun(importRun{
+		Dataset:      dataset,
+		StartedAt:    time.Now().UTC(),
+		Source:       source,
+		SnapshotDate: NullDate{sql.NullTime{Time: snapshot, Valid: true}},
+		Mode:         mode,
+	})
+	if err != nil {
+		return nil, err
+	}
+	run.id = id
+	return run, nil
+}
+
+// finish records the outcome of the run with the counts so far, message is
+// the error of a failed run.
+func (r *runRecorder) finish(outcome, message string) {
+	finished := time.Now().UTC()
+	run := importRun{
+		ID:         r.id,
+		FinishedAt: &finished,
+		SourceSize: r.size,
+		Read:       r.stats.read.Load(),
+		Inserted:   r.stats.inserted.Load(),
+		Updated:    r.stats.updated.Load(),
+		Unchanged:  r.stats.unchanged.Load(),
+		Rejected:   r.stats.rejected.Load(),
+		Failed:     r.stats.failed.Load(),
+		Outcome:    outcome,
+	}
+	if r.hash != "" {
+		run.SourceSHA256 = &r.hash
+	}
+	if message != "" {
+		run.Error = &message
+	}
+	if err := r.store.FinishImportRun(run); err != nil {
+		log.Println("Error recording the import run ", err)
+	}
+}
+
+// inserts a running import_runs row and returns its id. MySQL has no
+// RETURNING, the pgx driver has no LastInsertId.
+func insertImportRun(db *sql.DB, run importRun) (int64, error) {
+	query := "INSERT INTO import_runs (dataset, started_at, source, snapshot_date, mode, read_rows, inserted_rows, updated_rows, unchanged_rows, rejected_rows, failed_rows, outcome) VALUES (?, ?, ?, ?, ?, 0, 0, 0, 0, 0, 0, 'running')"
+	args := []any{run.Dataset, run.StartedAt, run.Source, run.SnapshotDate, run.Mode}
+	if _, ok := db.Driver().(*mysql.MySQLDriver); ok {
+		result, err := db.Exec(query, args...)
+		if err != nil {
+			return 0, err
+		}
+		return result.LastInsertId()
+	}
+	var id int64
+	err := db.QueryRow(query+" RETURNING id", args...).Scan(&id)
+	return id, err
+}
+
+// updates the import_runs row of run with its end, source, counts and outcome
+func finishImportRun(db *sql.DB, run importRun) error {
+	_, err := db.Exec("UPDATE import_runs SET finished_at = ?, source_size = ?, source_sha256 = ?, read_rows = ?, inserted_rows = ?, updated_rows = ?, unchanged_rows = ?, rejected_rows = ?, failed_rows = ?, outcome = ?, error = ? WHERE id = ?",
+		run.FinishedAt, run.SourceSize, run.SourceSHA256, run.Read, run.Inserted, run.Updated, run.Unchanged,
+		run.Rejected, run.Failed, run.Outcome, run.Error, run.ID)
+	return err
+}
+
+// fatal records the run as failed and exits like log.Fatal. Only the first
+// call records, concurrent calls wait for the exit.
+func (r *runRecorder) fatal(v ...any) {
+	message := fmt.Sprint(v...)
+	r.failed.Do(func() {
+		r.finish("failed", message)
+		log.Fatal(message)
+	})
+}
+
+func (r *runRecorder) fatalf(format string, v ...any) {
+	r.fatal(fmt.Sprintf(format, v...))
+}
+
+// fetches the newest import runs, newest first
+func getImportRuns(db *sql.DB, limit int) ([]importRun, error) {
+	rows, err := db.Query("SELECT id, dataset, started_at, finished_at, source, source_size, source_sha256, snapshot_date, mode, read_rows, inserted_rows, updated_rows, unchanged_rows, rejected_rows, failed_rows, outcome, error FROM import_runs ORDER BY id DESC LIMIT ?", limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	runs := []importRun{}
+	for rows.Next() {
+		run, err := scanImportRun(rows)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, run)
+	}
+	return runs, rows.Err()
+}
+
+// fetches the newest voertuigen import run that succeeded, sql.ErrNoRows when
+// there is none
+func getLastSucceededImportRun(db *sql.DB) (importRun, error) {
+	return scanImportRun(db.QueryRow("SELECT id, dataset, started_at, finished_at, source, source_size, source_sha256, snapshot_date, mode, read_rows, inserted_rows, updated_rows, unchanged_rows, rejected_rows, failed_rows, outcome, error FROM import_runs WHERE dataset = 'voertuigen' AND outcome = 'succeeded' ORDER BY id DESC LIMIT 1"))
+}
+
+func scanImportRun(row interface{ Scan(...any) error }) (importRun, error) {
+	var run importRun
+	// NullDate also scans the text SQLite may return for a timestamp
+	var started, finished NullDate
+	err := row.Scan(&run.ID, &run.Dataset, &started, &finished, &run.Source, &run.SourceSize, &run.SourceSHA256, &run.SnapshotDate, &run.Mode,
+		&run.Read, &run.Inserted, &run.Updated, &run.Unchanged, &run.Rejected, &run.Failed, &run.Outcome, &run.Error)
+	run.StartedAt = started.Time
+	if finished.Valid {
+		run.FinishedAt = &finished.Time
+	}
+	return run, err
+}
+
+// runRuns lists the newest import runs.
+func runRuns(args []string) {
+	flags := flag.NewFlagSet("runs", flag.ExitOnError)
+	limit := flags.Int("limit", defaultRunsLimit, "number of runs to show")
+	flags.Parse(args)
+
+	store, err := openStore()
+	if err != nil {
+		log.Fatal("Error connecting to the database ", err)
+	}
+	runs, err := store.ImportRuns(*limit)
+	if err != nil {
+		log.Fatal("Error fetching import runs ", err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tDATASET\tSTARTED\tDURATION\tOUTCOME\tMODE\tSNAPSHOT\tREAD\tINSERTED\tUPDATED\tREJECTED\tFAILED\tSOURCE")
+	for _, run := range runs {
+		duration := "-"
+		if run.FinishedAt != nil {
+			duration = run.FinishedAt.Sub(run.StartedAt).Round(time.Second).String()
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%s\t%d\t%d\t%d\t%d\t%d\t%s\n", run.ID, run.Dataset, run.StartedAt.Format(time.DateTime), duration,
+			run.Outcome, run.Mode, run.SnapshotDate.Time.Format(time.DateOnly), run.Read, run.Inserted, run.Updated, run.Rejected, run.Failed, run.Source)
+	}
+	w.Flush()
+}
@@ -0,0 +1,126 @@
+// Package kenteken normalizes, validates and formats Dutch license plates.
+//
+// The RDW stores plates without dashes in upper case ("XX123B"), while users
+// type them in all kinds of ways ("xx-123-b", "XX 123 B"). Normalize turns any
+// of those into the RDW form so lookups never miss because of formatting.
+package kenteken
+
+import (
+	"errors"
+	"strings"
+)
+
+var (
+	ErrEmpty          = errors.New("kenteken is empty")
+	ErrInvalidPattern = errors.New("kenteken does not match any sidecode")
+	ErrForbiddenChar  = errors.New("kenteken contains a forbidden letter")
+)
+
+// forbiddenLetters are never issued on Dutch plates: vowels, C, Q and Y could
+// form words or be confused with digits.
+const forbiddenLetters = "AEIOUCQY"
+
+// sidecodes holds the layout of every sidecode, indexed by sidecode number.
+// 'X' stands for a letter, '9' for a digit and '-' for a dash.
+var sidecodes = [...]string{
+	1:  "XX-99-99",
+	2:  "99-99-XX",
+	3:  "99-XX-99",
+	4:  "XX-99-XX",
+	5:  "XX-XX-99",
+	6:  "99-XX-XX",
+	7:  "99-XXX-9",
+	8:  "9-XXX-99",
+	9:  "XX-999-X",
+	10: "X-999-XX",
+	11: "XXX-99-X",
+	12: "X-99-XXX",
+	13: "9-XX-999",
+	14: "999-XX-9",
+}
+
+// Normalize returns the plate in RDW form: upper case without dashes, spaces
+// or other separators. It does not validate the result.
+func Normalize(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+	for _, r := range strings.ToUpper(s) {
+		if isLetter(r) || isDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// Sidecode normalizes the plate and returns the number of the sidecode it
+// matches.
+func Sidecode(s string) (int, error) {
+	plate := Normalize(s)
+	if plate == "" {
+		return 0, ErrEmpty
+	}
+
+	for code := 1; code < len(sidecodes); code++ {
+		if matches(plate, sidecodes[code]) {
+			if strings.ContainsAny(plate, forbiddenLetters) {
+				return 0, ErrForbiddenChar
+			}
+			return code, nil
+		}
+	}
+	return 0, ErrInvalidPattern
+}
+
+// Validate normalizes the plate and returns it if it is a possible Dutch
+// plate.
+func Validate(s string) (string, error) {
+	if _, err := Sidecode(s); err != nil {
+		return "", err
+	}
+	return Normalize(s), nil
+}
+
+// Format returns the plate in its dashed display form, e.g. "XX-123-B".
+func Format(s string) (string, error) {
+	code, err := Sidecode(s)
+	if err != nil {
+		return "", err
+	}
+
+	plate := Normalize(s)
+	var b strings.Builder
+	i := 0
+	for _, c := range sidecodes[code] {
+		if c == '-' {
+			b.WriteByte('-')
+			continue
+		}
+		b.WriteByte(plate[i])
+		i++
+	}
+	return b.String(), nil
+}
+
+// matches reports whether a normalized plate fits the layout of a sidecode.
+func matches(plate, layout string) bool {
+	layout = strings.ReplaceAll(layout, "-", "")
+	if len(plate) != len(layout) {
+		return false
+	}
+
+	for i := 0; i < len(layout); i++ {
+		r := rune(plate[i])
+		if layout[i] == 'X' && !isLetter(r) || layout[i] == '9' && !isDigit(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func isLetter(r rune) bool {
+	return r >= 'A' && r <= 'Z'
+}
+
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
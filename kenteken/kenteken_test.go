@@ -0,0 +1,92 @@
+package kenteken
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := map[string]string{
+		"xx-123-b":  "XX123B",
+		"XX 123 B":  "XX123B",
+		" 12.ab.34": "12AB34",
+		"":          "",
+		"-- --":     "",
+		"éé12":      "12",
+	}
+	for input, want := range tests {
+		if got := Normalize(input); got != want {
+			t.Errorf("Normalize(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestSidecode(t *testing.T) {
+	tests := []struct {
+		plate string
+		code  int
+	}{
+		{"XX-99-99", 1},
+		{"99-99-XX", 2},
+		{"99-XX-99", 3},
+		{"XX-99-XX", 4},
+		{"XX-XX-99", 5},
+		{"99-XX-XX", 6},
+		{"99-XXX-9", 7},
+		{"9-XXX-99", 8},
+		{"XX-999-X", 9},
+		{"X-999-XX", 10},
+		{"XXX-99-X", 11},
+		{"X-99-XXX", 12},
+		{"9-XX-999", 13},
+		{"999-XX-9", 14},
+		{"gb-123-d", 9},
+	}
+	for _, test := range tests {
+		code, err := Sidecode(test.plate)
+		if err != nil || code != test.code {
+			t.Errorf("Sidecode(%q) = %d, %v, want %d", test.plate, code, err, test.code)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		plate string
+		want  string
+		err   error
+	}{
+		{"gb-123-d", "GB123D", nil},
+		{"1-TTT-23", "1TTT23", nil},
+		{"", "", ErrEmpty},
+		{" - ", "", ErrEmpty},
+		{"XX-12-3", "", ErrInvalidPattern},
+		{"XXX123", "", ErrInvalidPattern},
+		{"CDJ001", "", ErrInvalidPattern},
+		{"AA-123-B", "", ErrForbiddenChar},
+		{"12-QY-34", "", ErrForbiddenChar},
+	}
+	for _, test := range tests {
+		got, err := Validate(test.plate)
+		if got != test.want || !errors.Is(err, test.err) {
+			t.Errorf("Validate(%q) = %q, %v, want %q, %v", test.plate, got, err, test.want, test.err)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := map[string]string{
+		"gb123d": "GB-123-D",
+		"1TTT23": "1-TTT-23",
+		"99XXX9": "99-XXX-9",
+		"bd12bd": "BD-12-BD",
+	}
+	for input, want := range tests {
+		if got, err := Format(input); err != nil || got != want {
+			t.Errorf("Format(%q) = %q, %v, want %q", input, got, err, want)
+		}
+	}
+	if _, err := Format("AA-123-B"); !errors.Is(err, ErrForbiddenChar) {
+		t.Errorf("Format(%q) returned %v, want %v", "AA-123-B", err, ErrForbiddenChar)
+	}
+}
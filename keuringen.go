@@ -0,0 +1,156 @@
+package main
+
+import (
+	"database/sql"
+	"sort"
+)
+
+// apkDatasets are the RDW datasets on APK inspections: every inspection
+// reported per kenteken, the defects found during them and the code table
+// describing those defects. Their tables are defined in the migrations.
+var apkDatasets = []rdwDataset{
+	{
+		name:     "keuringen",
+		resource: "sgfe-77wx",
+		table:    "apk_keuringen",
+		key:      []string{"kenteken", "meld_datum_door_keuringsinstantie", "meld_tijd_door_keuringsinstantie"},
+		columns: []datasetColumn{
+			{"kenteken", textColumn},
+			{"meld_datum_door_keuringsinstantie", dateColumn},
+			{"meld_tijd_door_keuringsinstantie", intColumn},
+			{"vervaldatum_keuring", dateColumn},
+			{"soort_erkenning_keuringsinstantie", textColumn},
+			{"soort_erkenning_omschrijving", textColumn},
+			{"soort_melding_ki_omschrijving", textColumn},
+		},
+	},
+	{
+		name:     "geconstateerde_gebreken",
+		resource: "a34c-vvps",
+		table:    "apk_geconstateerde_gebreken",
+		key:      []string{"kenteken", "meld_datum_door_keuringsinstantie", "meld_tijd_door_keuringsinstantie", "gebrek_identificatie"},
+		columns: []datasetColumn{
+			{"kenteken", textColumn},
+			{"meld_datum_door_keuringsinstantie", dateColumn},
+			{"meld_tijd_door_keuringsinstantie", intColumn},
+			{"gebrek_identificatie", textColumn},
+			{"soort_erkenning_keuringsinstantie", textColumn},
+			{"soort_erkenning_omschrijving", textColumn},
+			{"aantal_gebreken_geconstateerd", intColumn},
+		},
+	},
+	{
+		name:     "gebreken",
+		resource: "hx2c-gt7k",
+		table:    "apk_gebreken",
+		key:      []string{"gebrek_identificatie"},
+		columns: []datasetColumn{
+			{"gebrek_identificatie", textColumn},
+			{"ingangsdatum_gebrek", dateColumn},
+			{"einddatum_gebrek", dateColumn},
+			{"gebrek_paragraaf_nummer", textColumn},
+			{"gebrek_artikel_nummer", textColumn},
+			{"gebrek_omschrijving", longTextColumn},
+		},
+	},
+}
+
+// keuring is one APK inspection in the timeline of a vehicle.
+type keuring struct {
+	MeldDatum                       NullDate `json:"meld_datum_door_keuringsinstantie"`
+	MeldTijd                        NullInt  `json:"meld_tijd_door_keuringsinstantie"`
+	VervaldatumKeuring              NullDate `json:"vervaldatum_keuring"`
+	SoortErkenningKeuringsinstantie string   `json:"soort_erkenning_keuringsinstantie"`
+	SoortErkenningOmschrijving      string   `json:"soort_erkenning_omschrijving"`
+	SoortMeldingKiOmschrijving      string   `json:"soort_melding_ki_omschrijving"`
+	Gebreken                        []gebrek `json:"gebreken"`
+}
+
+// gebrek is a defect found during an inspection, described by the code table.
+type gebrek struct {
+	GebrekIdentificatie         string  `json:"gebrek_identificatie"`
+	AantalGebrekenGeconstateerd NullInt `json:"aantal_gebreken_geconstateerd"`
+	GebrekParagraafNummer       string  `json:"gebrek_paragraaf_nummer"`
+	GebrekArtikelNummer         string  `json:"gebrek_artikel_nummer"`
+	GebrekOmschrijving          string  `json:"gebrek_omschrijving"`
+}
+
+// fetches the APK inspections of a vehicle with their defects, oldest first
+func getVoertuigKeuringen(db *sql.DB, kenteken string) ([]keuring, error) {
+	rows, err := db.Query(
+		"SELECT meld_datum_door_keuringsinstantie, meld_tijd_door_keuringsinstantie, vervaldatum_keuring, "+
+			"COALESCE(soort_erkenning_keuringsinstantie, ''), COALESCE(soort_erkenning_omschrijving, ''), COALESCE(soort_melding_ki_omschrijving, '') "+
+			"FROM apk_keuringen WHERE kenteken = ? "+
+			"ORDER BY meld_datum_door_keuringsinstantie, meld_tijd_door_keuringsinstantie",
+		kenteken,
+	)
+	if err != nil {
+		return nil, err
+	}
+	keuringen := []keuring{}
+	for rows.Next() {
+		k := keuring{Gebreken: []gebrek{}}
+		err := rows.Scan(&k.MeldDatum, &k.MeldTijd, &k.VervaldatumKeuring,
+			&k.SoortErkenningKeuringsinstantie, &k.SoortErkenningOmschrijving, &k.SoortMeldingKiOmschrijving)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		keuringen = append(keuringen, k)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = db.Query(
+		"SELECT g.meld_datum_door_keuringsinstantie, g.meld_tijd_door_keuringsinstantie, g.gebrek_identificatie, g.aantal_gebreken_geconstateerd, "+
+			"COALESCE(c.gebrek_paragraaf_nummer, ''), COALESCE(c.gebrek_artikel_nummer, ''), COALESCE(c.gebrek_omschrijving, '') "+
+			"FROM apk_geconstateerde_gebreken g LEFT JOIN apk_gebreken c ON c.gebrek_identificatie = g.gebrek_identificatie "+
+			"WHERE g.kenteken = ? ORDER BY g.gebrek_identificatie",
+		kenteken,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var datum NullDate
+		var tijd NullInt
+		var g gebrek
+		err := rows.Scan(&datum, &tijd, &g.GebrekIdentificatie, &g.AantalGebrekenGeconstateerd,
+			&g.GebrekParagraafNummer, &g.GebrekArtikelNummer, &g.GebrekOmschrijving)
+		if err != nil {
+			return nil, err
+		}
+
+		// attach the defect to its inspection, keep it under a bare entry when
+		// the inspection itself is missing from apk_keuringen
+		i := 0
+		for i < len(keuringen) && !keuringen[i].reportedAt(datum, tijd) {
+			i++
+		}
+		if i == len(keuringen) {
+			keuringen = append(keuringen, keuring{MeldDatum: datum, MeldTijd: tijd, Gebreken: []gebrek{}})
+		}
+		keuringen[i].Gebreken = append(keuringen[i].Gebreken, g)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(keuringen, func(i, j int) bool {
+		if !keuringen[i].MeldDatum.Time.Equal(keuringen[j].MeldDatum.Time) {
+			return keuringen[i].MeldDatum.Time.Before(keuringen[j].MeldDatum.Time)
+		}
+		return keuringen[i].MeldTijd.Int64 < keuringen[j].MeldTijd.Int64
+	})
+	return keuringen, nil
+}
+
+// reportedAt reports whether the inspection was reported at the given date
+// and time, which together with the kenteken identify an inspection.
+func (k keuring) reportedAt(datum NullDate, tijd NullInt) bool {
+	return k.MeldDatum.Valid == datum.Valid && k.MeldDatum.Time.Equal(datum.Time) && k.MeldTijd == tijd
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// loadDataStreams numbers the reader handlers of concurrent loads.
+var loadDataStreams atomic.Int64
+
+// tsvEscaper escapes the characters LOAD DATA reads specially with ESCAPED BY
+// '\\'.
+var tsvEscaper = strings.NewReplacer("\\", "\\\\", "\t", "\\t", "\n", "\\n", "\r", "\\r", "\x00", "\\0")
+
+// LoadRecords streams the records into table with LOAD DATA LOCAL INFILE, as
+// TSV through a reader handler of the driver, until records is closed. It
+// returns the number of rows loaded. With LOCAL, MySQL skips rows with a
+// duplicate kenteken instead of failing the load, so these are not counted.
+// The server needs local_infile enabled.
+func (s *mysqlStore) LoadRecords(table string, records <-chan RDWRecord, snapshot time.Time) (int64, error) {
+	name := "voertuigen_" + strconv.FormatInt(loadDataStreams.Add(1), 10)
+	pr, pw := io.Pipe()
+	mysql.RegisterReaderHandler(name, func() io.Reader { return pr })
+	defer mysql.DeregisterReaderHandler(name)
+
+	go func() {
+		w := bufio.NewWriterSize(pw, 1<<20)
+		var err error
+		// keep draining records when the load stopped, so the importer
+		// does not block
+		for record := range records {
+			if err == nil {
+				err = writeTSVRow(w, &record, snapshot)
+			}
+		}
+		if err == nil {
+			err = w.Flush()
+		}
+		pw.CloseWithError(err)
+	}()
+
+	result, err := s.db.Exec(loadDataSQL(table, name))
+	pr.Close()
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func loadDataSQL(table, handler string) string {
+	return "LOAD DATA LOCAL INFILE 'Reader::" + handler + "' INTO TABLE " + table +
+		" CHARACTER SET utf8mb4 FIELDS TERMINATED BY '\\t' ESCAPED BY '\\\\' LINES TERMINATED BY '\\n'" +
+		" (" + strings.Join(voertuigInsertColumns(), ", ") + ")"
+}
+
+// writeTSVRow writes the fields of record followed by the meta columns as one
+// line, with \N for NULL. The values are the ones the INSERT statements write.
+func writeTSVRow(w *bufio.Writer, record *RDWRecord, snapshot time.Time) error {
+	for i, column := range voertuigColumns {
+		if i > 0 {
+			w.WriteByte('\t')
+		}
+		if value, ok := fieldString(column.field(record)); ok {
+			tsvEscaper.WriteString(w, value)
+		} else {
+			w.WriteString(`\N`)
+		}
+	}
+	_, err := w.WriteString("\t" + record.hash() + "\t" + snapshot.Format(time.DateOnly) + "\n")
+	return err
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+func main() {
+	command, args := "import", []string{}
+	if len(os.Args) > 1 {
+		command, args = os.Args[1], os.Args[2:]
+	}
+
+	switch command {
+	case "import":
+		runImport(args)
+	case "import-dataset":
+		runDatasetImport(args)
+	case "rollback":
+		runRollback(args)
+	case "serve":
+		runServer()
+	case "bench":
+		runBench(args)
+	case "runs":
+		runRuns(args)
+	case "migrate":
+		runMigrate(args)
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command %q, expected one of: import, import-dataset, rollback, serve, bench, runs, migrate\n", command)
+		os.Exit(2)
+	}
+}
@@ -0,0 +1,367 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// migrationFiles holds the schema of every store as versioned migrations,
+// migrations/<store>/<version>_<name>.up.sql with a .down.sql that reverts it.
+//
+//go:embed migrations
+var migrationFiles embed.FS
+
+const (
+	// migrationLockTimeout is how long migrate waits for another instance
+	// that is migrating the same database.
+	migrationLockTimeout = time.Minute
+
+	createSchemaVersionSQL = "CREATE TABLE IF NOT EXISTS schema_version (version INTEGER NOT NULL PRIMARY KEY, name VARCHAR(255) NOT NULL, applied_at TIMESTAMP NOT NULL)"
+)
+
+// errMigrationLocked is returned when another instance holds the migration
+// lock for longer than migrationLockTimeout.
+var errMigrationLocked = errors.New("another instance is migrating the database")
+
+// schemaMigrator is implemented by the stores: where their migrations are and
+// how they keep two instances from migrating the same database at once.
+type schemaMigrator interface {
+	// MigrationsDir is the directory under migrations with the migrations of
+	// the store.
+	MigrationsDir() string
+
+	// LockMigrations takes the migration lock on conn, waiting until ctx is
+	// done. The returned function releases it, with commit false after a
+	// failed migration.
+	LockMigrations(ctx context.Context, conn *sql.Conn) (func(commit bool) error, error)
+
+	// TransactionalDDL tells whether a migration can run in a transaction
+	// together with its schema_version row.
+	TransactionalDDL() bool
+
+	// Conn returns a connection of its own to run the migrations on.
+	Conn(ctx context.Context) (*sql.Conn, error)
+}
+
+// migration is one version of the schema.
+type migration struct {
+	version int
+	name    string
+	up      string
+	down    string
+}
+
+// contextExecer is implemented by both *sql.Conn and *sql.Tx.
+type contextExecer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// loadMigrations reads the migrations in dir, sorted by version.
+func loadMigrations(dir string) ([]migration, error) {
+	dir = path.Join("migrations", dir)
+	entries, err := fs.ReadDir(migrationFiles, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*migration{}
+	for _, entry := range entries {
+		base, direction, ok := strings.Cut(strings.TrimSuffix(entry.Name(), ".sql"), ".")
+		number, name, named := strings.Cut(base, "_")
+		version, err := strconv.Atoi(number)
+		if !ok || !named || err != nil || (direction != "up" && direction != "down") {
+			return nil, fmt.Errorf("%s/%s is not named <version>_<name>.up.sql or .down.sql", dir, entry.Name())
+		}
+		script, err := fs.ReadFile(migrationFiles, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &migration{version: version, name: name}
+			byVersion[version] = m
+		}
+		if direction == "up" {
+			m.up = string(script)
+		} else {
+			m.down = string(script)
+		}
+	}
+
+	migrations := make([]migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.up == "" || m.down == "" {
+			return nil, fmt.Errorf("migration %d_%s in %s needs both an up and a down script", m.version, m.name, dir)
+		}
+		migrations = append(migrations, *m)
+	}
+	slices.SortFunc(migrations, func(a, b migration) int { return a.version - b.version })
+	return migrations, nil
+}
+
+// sqlStatements splits a migration script into its statements, which end
+// with a semicolon at the end of a line.
+func sqlStatements(script string) []string {
+	var statements []string
+	for _, statement := range strings.SplitAfter(script, ";\n") {
+		statement = strings.TrimSpace(statement)
+		if strings.Trim(statement, ";") != "" {
+			statements = append(statements, statement)
+		}
+	}
+	return statements
+}
+
+// withMigrationLock runs migrate on a connection holding the migration lock of
+// the store.
+func withMigrationLock(store schemaMigrator, migrate func(conn *sql.Conn) error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), migrationLockTimeout)
+	defer cancel()
+	conn, err := store.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	unlock, err := store.LockMigrations(ctx, conn)
+	if err != nil {
+		return fmt.Errorf("taking the migration lock: %w", err)
+	}
+	err = migrate(conn)
+	if unlockErr := unlock(err == nil); err == nil {
+		err = unlockErr
+	}
+	return err
+}
+
+// appliedMigrations returns when each applied version was applied, creating
+// schema_version when it does not exist yet.
+func appliedMigrations(conn *sql.Conn) (map[int]time.Time, error) {
+	ctx := context.Background()
+	if _, err := conn.ExecContext(ctx, createSchemaVersionSQL); err != nil {
+		return nil, err
+	}
+	rows, err := conn.QueryContext(ctx, "SELECT version, applied_at FROM schema_version")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := map[int]time.Time{}
+	for rows.Next() {
+		var version int
+		// NullDate also scans the text SQLite may return for a timestamp
+		var at NullDate
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, err
+		}
+		applied[version] = at.Time
+	}
+	return applied, rows.Err()
+}
+
+// tableExists tells whether table exists, by selecting nothing from it.
+func tableExists(conn *sql.Conn, table string) bool {
+	_, err := conn.ExecContext(context.Background(), "SELECT 1 FROM "+table+" WHERE 1 = 0")
+	return err == nil
+}
+
+// migrateUp applies the migrations that are not applied yet, in order, and
+// returns how many it applied. A database created from the schema before it
+// was versioned has voertuigen but no schema_version rows. Its first
+// migration, which is that schema, is recorded as applied without running it
+// and the later ones bring it up to date.
+func migrateUp(store schemaMigrator) (int, error) {
+	migrations, err := loadMigrations(store.MigrationsDir())
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	err = withMigrationLock(store, func(conn *sql.Conn) error {
+		applied, err := appliedMigrations(conn)
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 && len(migrations) > 0 && tableExists(conn, voertuigenTable) {
+			log.Printf("Found a schema without schema_version, recording migration %d_%s as applied", migrations[0].version, migrations[0].name)
+			if err := recordMigration(conn, migrations[0]); err != nil {
+				return err
+			}
+			applied[migrations[0].version] = time.Now()
+		}
+
+		for _, m := range migrations {
+			if _, ok := applied[m.version]; ok {
+				continue
+			}
+			log.Printf("Applying migration %d_%s", m.version, m.name)
+			err := runMigration(conn, store.TransactionalDDL(), m.up, func(tx contextExecer) error {
+				return recordMigration(tx, m)
+			})
+			if err != nil {
+				return fmt.Errorf("migration %d_%s: %w", m.version, m.name, err)
+			}
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
+
+// migrateDown reverts the newest steps applied migrations and returns how
+// many it reverted.
+func migrateDown(store schemaMigrator, steps int) (int, error) {
+	migrations, err := loadMigrations(store.MigrationsDir())
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	err = withMigrationLock(store, func(conn *sql.Conn) error {
+		applied, err := appliedMigrations(conn)
+		if err != nil {
+			return err
+		}
+		for i := len(migrations) - 1; i >= 0 && count < steps; i-- {
+			m := migrations[i]
+			if _, ok := applied[m.version]; !ok {
+				continue
+			}
+			log.Printf("Reverting migration %d_%s", m.version, m.name)
+			err := runMigration(conn, store.TransactionalDDL(), m.down, func(tx contextExecer) error {
+				_, err := tx.ExecContext(context.Background(), "DELETE FROM schema_version WHERE version = ?", m.version)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("migration %d_%s: %w", m.version, m.name, err)
+			}
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
+
+// runMigration runs the statements of script and then record, in a single
+// transaction when the database supports transactional DDL.
+func runMigration(conn *sql.Conn, transactional bool, script string, record func(tx contextExecer) error) error {
+	ctx := context.Background()
+	var tx contextExecer = conn
+	if transactional {
+		sqlTx, err := conn.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer sqlTx.Rollback()
+		tx = sqlTx
+	}
+
+	for _, statement := range sqlStatements(script) {
+		if _, err := tx.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+	if err := record(tx); err != nil {
+		return err
+	}
+	if sqlTx, ok := tx.(*sql.Tx); ok {
+		return sqlTx.Commit()
+	}
+	return nil
+}
+
+func recordMigration(tx contextExecer, m migration) error {
+	_, err := tx.ExecContext(context.Background(), "INSERT INTO schema_version (version, name, applied_at) VALUES (?, ?, ?)", m.version, m.name, time.Now().UTC())
+	return err
+}
+
+// runMigrate manages the schema: migrate up applies the pending migrations,
+// migrate down [-steps 1] reverts the newest ones and migrate status lists
+// them.
+func runMigrate(args []string) {
+	if len(args) == 0 {
+		log.Fatal("Expected migrate up, down or status")
+	}
+	command := args[0]
+	flags := flag.NewFlagSet("migrate "+command, flag.ExitOnError)
+	steps := flags.Int("steps", 1, "number of migrations to revert")
+	flags.Parse(args[1:])
+
+	store, err := openStore()
+	if err != nil {
+		log.Fatal("Error connecting to the database ", err)
+	}
+	migrator, ok := store.(schemaMigrator)
+	if !ok {
+		log.Fatal("The store has no migrations")
+	}
+
+	switch command {
+	case "up":
+		applied, err := migrateUp(migrator)
+		if err != nil {
+			log.Fatal("Error migrating ", err)
+		}
+		log.Printf("Applied %d migrations", applied)
+	case "down":
+		reverted, err := migrateDown(migrator, *steps)
+		if err != nil {
+			log.Fatal("Error migrating ", err)
+		}
+		log.Printf("Reverted %d migrations", reverted)
+	case "status":
+		if err := printMigrationStatus(migrator); err != nil {
+			log.Fatal("Error reading the schema version ", err)
+		}
+	default:
+		log.Fatalf("Unknown migrate command %q, expected up, down or status", command)
+	}
+}
+
+// printMigrationStatus lists every migration with when it was applied.
+func printMigrationStatus(store schemaMigrator) error {
+	migrations, err := loadMigrations(store.MigrationsDir())
+	if err != nil {
+		return err
+	}
+	conn, err := store.Conn(context.Background())
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	applied, err := appliedMigrations(conn)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED")
+	for _, m := range migrations {
+		status := "pending"
+		if at, ok := applied[m.version]; ok {
+			status = at.Format(time.DateTime)
+			delete(applied, m.version)
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\n", m.version, m.name, status)
+	}
+	// versions applied by a newer build
+	for version, at := range applied {
+		fmt.Fprintf(w, "%d\t?\t%s (unknown to this build)\n", version, at.Format(time.DateTime))
+	}
+	return w.Flush()
+}
@@ -1,7 +1,3 @@
-CREATE DATABASE IF NOT EXISTS kentekens;
-
-USE kentekens;
-
 CREATE TABLE voertuigen (
                             kenteken VARCHAR(255),
                             voertuigsoort VARCHAR(255) NULL,
@@ -102,4 +98,4 @@ CREATE TABLE voertuigen (
                             PRIMARY KEY (`kenteken`)
 );
 
-ALTER TABLE voertuigen CONVERT TO CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci;
\ No newline at end of file
+ALTER TABLE voertuigen CONVERT TO CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci;
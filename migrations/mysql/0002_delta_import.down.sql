@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS voertuigen_changes;
+ALTER TABLE voertuigen DROP COLUMN removed_at, DROP COLUMN snapshot_date, DROP COLUMN row_hash;
@@ -0,0 +1,12 @@
+ALTER TABLE voertuigen ADD COLUMN row_hash CHAR(64) NULL, ADD COLUMN snapshot_date DATE NULL, ADD COLUMN removed_at DATE NULL;
+
+CREATE TABLE voertuigen_changes (
+                            id BIGINT AUTO_INCREMENT,
+                            kenteken VARCHAR(255) NOT NULL,
+                            change_type ENUM('added', 'changed', 'removed') NOT NULL,
+                            changed_columns TEXT NULL,
+                            snapshot_date DATE NOT NULL,
+                            PRIMARY KEY (`id`),
+                            KEY `idx_voertuigen_changes_kenteken` (`kenteken`),
+                            KEY `idx_voertuigen_changes_snapshot_date` (`snapshot_date`)
+) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci;
@@ -0,0 +1,11 @@
+CREATE TABLE voertuigen_history (
+                            id BIGINT AUTO_INCREMENT,
+                            kenteken VARCHAR(255) NOT NULL,
+                            valid_from DATE NULL,
+                            valid_to DATE NULL,
+                            row_hash CHAR(64) NOT NULL,
+                            changed_columns TEXT NULL,
+                            record JSON NOT NULL,
+                            PRIMARY KEY (`id`),
+                            KEY `idx_voertuigen_history_kenteken` (`kenteken`, `valid_to`)
+) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci;
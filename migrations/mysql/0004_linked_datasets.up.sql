@@ -0,0 +1,84 @@
+CREATE TABLE voertuigen_assen (
+                            kenteken VARCHAR(255) NOT NULL,
+                            as_nummer INT NOT NULL,
+                            aantal_assen INT NULL,
+                            aangedreven_as VARCHAR(255) NULL,
+                            hefas VARCHAR(255) NULL,
+                            plaatscode_as VARCHAR(255) NULL,
+                            spoorbreedte INT NULL,
+                            weggedrag_code VARCHAR(255) NULL,
+                            wettelijk_toegestane_maximum_aslast INT NULL,
+                            technisch_toegestane_maximum_aslast INT NULL,
+                            afstand_tot_volgende_as_voertuig INT NULL,
+                            afstand_tot_volgende_as_voertuig_minimum INT NULL,
+                            afstand_tot_volgende_as_voertuig_maximum INT NULL,
+                            maximum_last_as_technisch_maximaal INT NULL,
+                            maximum_last_as_technisch_minimaal INT NULL,
+                            PRIMARY KEY (`kenteken`, `as_nummer`)
+) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci;
+
+CREATE TABLE voertuigen_brandstof (
+                            kenteken VARCHAR(255) NOT NULL,
+                            brandstof_volgnummer INT NOT NULL,
+                            brandstof_omschrijving VARCHAR(255) NULL,
+                            brandstofverbruik_buiten DECIMAL(12, 4) NULL,
+                            brandstofverbruik_gecombineerd DECIMAL(12, 4) NULL,
+                            brandstofverbruik_stad DECIMAL(12, 4) NULL,
+                            co2_uitstoot_gecombineerd DECIMAL(12, 4) NULL,
+                            co2_uitstoot_gewogen DECIMAL(12, 4) NULL,
+                            geluidsniveau_rijdend DECIMAL(12, 4) NULL,
+                            geluidsniveau_stationair DECIMAL(12, 4) NULL,
+                            emissiecode_omschrijving VARCHAR(255) NULL,
+                            milieuklasse_eg_goedkeuring_licht VARCHAR(255) NULL,
+                            milieuklasse_eg_goedkeuring_zwaar VARCHAR(255) NULL,
+                            uitstoot_deeltjes_licht DECIMAL(12, 4) NULL,
+                            uitstoot_deeltjes_zwaar DECIMAL(12, 4) NULL,
+                            nettomaximumvermogen DECIMAL(12, 4) NULL,
+                            nominaal_continu_maximumvermogen DECIMAL(12, 4) NULL,
+                            roetuitstoot DECIMAL(12, 4) NULL,
+                            toerental_geluidsniveau DECIMAL(12, 4) NULL,
+                            emis_deeltjes_type1_wltp DECIMAL(12, 4) NULL,
+                            emissie_co2_gecombineerd_wltp DECIMAL(12, 4) NULL,
+                            emis_co2_gewogen_gecombineerd_wltp DECIMAL(12, 4) NULL,
+                            brandstof_verbruik_gecombineerd_wltp DECIMAL(12, 4) NULL,
+                            brandstof_verbruik_gewogen_gecombineerd_wltp DECIMAL(12, 4) NULL,
+                            elektrisch_verbruik_enkel_elektrisch_wltp DECIMAL(12, 4) NULL,
+                            actie_radius_enkel_elektrisch_wltp DECIMAL(12, 4) NULL,
+                            actie_radius_enkel_elektrisch_stad_wltp DECIMAL(12, 4) NULL,
+                            elektrisch_verbruik_extern_opladen_wltp DECIMAL(12, 4) NULL,
+                            actie_radius_extern_opladen_wltp DECIMAL(12, 4) NULL,
+                            actie_radius_extern_opladen_stad_wltp DECIMAL(12, 4) NULL,
+                            max_vermogen_15_minuten DECIMAL(12, 4) NULL,
+                            max_vermogen_60_minuten DECIMAL(12, 4) NULL,
+                            netto_max_vermogen_elektrisch DECIMAL(12, 4) NULL,
+                            klasse_hybride_elektrisch_voertuig VARCHAR(255) NULL,
+                            opgegeven_maximum_snelheid DECIMAL(12, 4) NULL,
+                            uitlaatemissieniveau VARCHAR(255) NULL,
+                            PRIMARY KEY (`kenteken`, `brandstof_volgnummer`)
+) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci;
+
+CREATE TABLE voertuigen_carrosserie (
+                            kenteken VARCHAR(255) NOT NULL,
+                            carrosserie_volgnummer INT NOT NULL,
+                            carrosserietype VARCHAR(255) NULL,
+                            type_carrosserie_europese_omschrijving VARCHAR(255) NULL,
+                            PRIMARY KEY (`kenteken`, `carrosserie_volgnummer`)
+) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci;
+
+CREATE TABLE voertuigen_carrosserie_specifiek (
+                            kenteken VARCHAR(255) NOT NULL,
+                            carrosserie_volgnummer INT NOT NULL,
+                            carrosserie_voertuig_nummer_code_volgnummer INT NOT NULL,
+                            carrosseriecode VARCHAR(255) NULL,
+                            carrosserie_voertuig_nummer_europese_omschrijving VARCHAR(255) NULL,
+                            PRIMARY KEY (`kenteken`, `carrosserie_volgnummer`, `carrosserie_voertuig_nummer_code_volgnummer`)
+) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci;
+
+CREATE TABLE voertuigen_voertuigklasse (
+                            kenteken VARCHAR(255) NOT NULL,
+                            carrosserie_volgnummer INT NOT NULL,
+                            carrosserie_klasse_volgnummer INT NOT NULL,
+                            voertuigklasse VARCHAR(255) NULL,
+                            voertuigklasse_omschrijving VARCHAR(255) NULL,
+                            PRIMARY KEY (`kenteken`, `carrosserie_volgnummer`, `carrosserie_klasse_volgnummer`)
+) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci;
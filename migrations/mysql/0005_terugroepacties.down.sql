@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS terugroepacties_herstel;
+DROP TABLE IF EXISTS terugroepacties_risico;
+DROP TABLE IF EXISTS terugroepacties;
+DROP TABLE IF EXISTS terugroepacties_status;
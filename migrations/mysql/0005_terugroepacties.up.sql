@@ -0,0 +1,28 @@
+CREATE TABLE terugroepacties_status (
+                            kenteken VARCHAR(255) NOT NULL,
+                            referentiecode_rdw VARCHAR(255) NOT NULL,
+                            code_status VARCHAR(255) NULL,
+                            status VARCHAR(255) NULL,
+                            PRIMARY KEY (`kenteken`, `referentiecode_rdw`)
+) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci;
+
+CREATE TABLE terugroepacties (
+                            referentiecode_rdw VARCHAR(255) NOT NULL,
+                            referentiecode_fabrikant VARCHAR(255) NULL,
+                            publicatiedatum_rdw DATE NULL,
+                            omschrijving_defect TEXT NULL,
+                            omschrijving_gevolg TEXT NULL,
+                            PRIMARY KEY (`referentiecode_rdw`)
+) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci;
+
+CREATE TABLE terugroepacties_risico (
+                            referentiecode_rdw VARCHAR(255) NOT NULL,
+                            risico_omschrijving TEXT NULL,
+                            KEY `idx_terugroepacties_risico_referentiecode_rdw` (`referentiecode_rdw`)
+) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci;
+
+CREATE TABLE terugroepacties_herstel (
+                            referentiecode_rdw VARCHAR(255) NOT NULL,
+                            herstel_omschrijving TEXT NULL,
+                            KEY `idx_terugroepacties_herstel_referentiecode_rdw` (`referentiecode_rdw`)
+) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci;
@@ -0,0 +1,32 @@
+CREATE TABLE apk_keuringen (
+                            kenteken VARCHAR(255) NOT NULL,
+                            meld_datum_door_keuringsinstantie DATE NOT NULL,
+                            meld_tijd_door_keuringsinstantie INT NOT NULL,
+                            vervaldatum_keuring DATE NULL,
+                            soort_erkenning_keuringsinstantie VARCHAR(255) NULL,
+                            soort_erkenning_omschrijving VARCHAR(255) NULL,
+                            soort_melding_ki_omschrijving VARCHAR(255) NULL,
+                            PRIMARY KEY (`kenteken`, `meld_datum_door_keuringsinstantie`, `meld_tijd_door_keuringsinstantie`)
+) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci;
+
+CREATE TABLE apk_geconstateerde_gebreken (
+                            kenteken VARCHAR(255) NOT NULL,
+                            meld_datum_door_keuringsinstantie DATE NOT NULL,
+                            meld_tijd_door_keuringsinstantie INT NOT NULL,
+                            gebrek_identificatie VARCHAR(255) NOT NULL,
+                            soort_erkenning_keuringsinstantie VARCHAR(255) NULL,
+                            soort_erkenning_omschrijving VARCHAR(255) NULL,
+                            aantal_gebreken_geconstateerd INT NULL,
+                            PRIMARY KEY (`kenteken`, `meld_datum_door_keuringsinstantie`, `meld_tijd_door_keuringsinstantie`, `gebrek_identificatie`),
+                            KEY `idx_apk_geconstateerde_gebreken_gebrek` (`gebrek_identificatie`)
+) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci;
+
+CREATE TABLE apk_gebreken (
+                            gebrek_identificatie VARCHAR(255) NOT NULL,
+                            ingangsdatum_gebrek DATE NULL,
+                            einddatum_gebrek DATE NULL,
+                            gebrek_paragraaf_nummer VARCHAR(255) NULL,
+                            gebrek_artikel_nummer VARCHAR(255) NULL,
+                            gebrek_omschrijving TEXT NULL,
+                            PRIMARY KEY (`gebrek_identificatie`)
+) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci;
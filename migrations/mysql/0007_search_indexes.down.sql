@@ -0,0 +1,10 @@
+ALTER TABLE voertuigen
+    DROP KEY `idx_voertuigen_merk`,
+    DROP KEY `idx_voertuigen_handelsbenaming`,
+    DROP KEY `idx_voertuigen_voertuigsoort`,
+    DROP KEY `idx_voertuigen_eerste_kleur`,
+    DROP KEY `idx_voertuigen_datum_eerste_toelating`,
+    DROP KEY `idx_voertuigen_vervaldatum_apk`,
+    DROP KEY `idx_voertuigen_catalogusprijs`,
+    DROP KEY `idx_voertuigen_massa_rijklaar`,
+    DROP KEY `idx_voertuigen_cilinderinhoud`;
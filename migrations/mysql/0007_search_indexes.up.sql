@@ -0,0 +1,10 @@
+ALTER TABLE voertuigen
+    ADD KEY `idx_voertuigen_merk` (`merk`),
+    ADD KEY `idx_voertuigen_handelsbenaming` (`handelsbenaming`),
+    ADD KEY `idx_voertuigen_voertuigsoort` (`voertuigsoort`),
+    ADD KEY `idx_voertuigen_eerste_kleur` (`eerste_kleur`),
+    ADD KEY `idx_voertuigen_datum_eerste_toelating` (`datum_eerste_toelating`, `kenteken`),
+    ADD KEY `idx_voertuigen_vervaldatum_apk` (`vervaldatum_apk`, `kenteken`),
+    ADD KEY `idx_voertuigen_catalogusprijs` (`catalogusprijs`, `kenteken`),
+    ADD KEY `idx_voertuigen_massa_rijklaar` (`massa_rijklaar`, `kenteken`),
+    ADD KEY `idx_voertuigen_cilinderinhoud` (`cilinderinhoud`, `kenteken`);
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS import_state (
+                            target_table VARCHAR(255) NOT NULL,
+                            file_sha256 CHAR(64) NOT NULL,
+                            mode VARCHAR(255) NOT NULL,
+                            snapshot_date DATE NOT NULL,
+                            byte_offset BIGINT NOT NULL,
+                            line_number BIGINT NOT NULL,
+                            read_rows BIGINT NOT NULL,
+                            rejected_rows BIGINT NOT NULL,
+                            failed_rows BIGINT NOT NULL,
+                            inserted_rows BIGINT NOT NULL,
+                            updated_rows BIGINT NOT NULL,
+                            unchanged_rows BIGINT NOT NULL,
+                            updated_at DATETIME NOT NULL,
+                            PRIMARY KEY (`target_table`)
+) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci;
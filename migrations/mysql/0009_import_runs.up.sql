@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS import_runs (
+                            id BIGINT AUTO_INCREMENT,
+                            started_at DATETIME NOT NULL,
+                            finished_at DATETIME NULL,
+                            source VARCHAR(1024) NOT NULL,
+                            source_size BIGINT NULL,
+                            source_sha256 CHAR(64) NULL,
+                            snapshot_date DATE NOT NULL,
+                            mode VARCHAR(255) NOT NULL,
+                            read_rows BIGINT NOT NULL,
+                            inserted_rows BIGINT NOT NULL,
+                            updated_rows BIGINT NOT NULL,
+                            unchanged_rows BIGINT NOT NULL,
+                            rejected_rows BIGINT NOT NULL,
+                            failed_rows BIGINT NOT NULL,
+                            outcome ENUM('running', 'succeeded', 'failed') NOT NULL,
+                            error TEXT NULL,
+                            PRIMARY KEY (`id`),
+                            KEY `idx_import_runs_outcome` (`outcome`, `id`)
+) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci;
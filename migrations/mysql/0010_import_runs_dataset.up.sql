@@ -0,0 +1 @@
+ALTER TABLE import_runs ADD COLUMN dataset VARCHAR(255) NOT NULL DEFAULT 'voertuigen' AFTER id;
@@ -0,0 +1,104 @@
+-- The tables of the MySQL schema in PostgreSQL. Text columns are CITEXT,
+-- case insensitive like the MySQL collation.
+
+CREATE EXTENSION IF NOT EXISTS citext;
+
+CREATE TABLE voertuigen (
+    kenteken CITEXT NOT NULL,
+    voertuigsoort CITEXT NULL,
+    merk CITEXT NULL,
+    handelsbenaming CITEXT NULL,
+    vervaldatum_apk DATE NULL,
+    datum_tenaamstelling DATE NULL,
+    bruto_bpm NUMERIC(10, 2) NULL,
+    inrichting CITEXT NULL,
+    aantal_zitplaatsen INTEGER NULL,
+    eerste_kleur CITEXT NULL,
+    tweede_kleur CITEXT NULL,
+    aantal_cilinders INTEGER NULL,
+    cilinderinhoud INTEGER NULL,
+    massa_ledig_voertuig INTEGER NULL,
+    toegestane_maximum_massa_voertuig INTEGER NULL,
+    massa_rijklaar INTEGER NULL,
+    maximum_massa_trekken_ongeremd INTEGER NULL,
+    maximum_trekken_massa_geremd INTEGER NULL,
+    datum_eerste_toelating DATE NULL,
+    datum_eerste_tenaamstelling_in_nederland DATE NULL,
+    wacht_op_keuren CITEXT NULL,
+    catalogusprijs NUMERIC(10, 2) NULL,
+    wam_verzekerd CITEXT NULL,
+    maximale_constructiesnelheid INTEGER NULL,
+    laadvermogen INTEGER NULL,
+    oplegger_geremd INTEGER NULL,
+    aanhangwagen_autonoom_geremd INTEGER NULL,
+    aanhangwagen_middenas_geremd INTEGER NULL,
+    aantal_staanplaatsen INTEGER NULL,
+    aantal_deuren INTEGER NULL,
+    aantal_wielen INTEGER NULL,
+    afstand_hart_koppeling_tot_achterzijde_voertuig INTEGER NULL,
+    afstand_voorzijde_voertuig_tot_hart_koppeling INTEGER NULL,
+    afwijkende_maximum_snelheid INTEGER NULL,
+    lengte INTEGER NULL,
+    breedte INTEGER NULL,
+    europese_voertuigcategorie CITEXT NULL,
+    europese_voertuigcategorie_toevoeging CITEXT NULL,
+    europese_uitvoeringcategorie_toevoeging CITEXT NULL,
+    plaats_chassisnummer CITEXT NULL,
+    technische_max_massa_voertuig INTEGER NULL,
+    type CITEXT NULL,
+    type_gasinstallatie CITEXT NULL,
+    typegoedkeuringsnummer CITEXT NULL,
+    variant CITEXT NULL,
+    uitvoering CITEXT NULL,
+    volgnummer_wijziging_eu_typegoedkeuring CITEXT NULL,
+    vermogen_massarijklaar NUMERIC(10, 2) NULL,
+    wielbasis INTEGER NULL,
+    export_indicator CITEXT NULL,
+    openstaande_terugroepactie_indicator CITEXT NULL,
+    vervaldatum_tachograaf DATE NULL,
+    taxi_indicator CITEXT NULL,
+    maximum_massa_samenstelling INTEGER NULL,
+    aantal_rolstoelplaatsen INTEGER NULL,
+    maximum_ondersteunende_snelheid INTEGER NULL,
+    jaar_laatste_registratie_tellerstand INTEGER NULL,
+    tellerstandoordeel CITEXT NULL,
+    code_toelichting_tellerstandoordeel CITEXT NULL,
+    tenaamstellen_mogelijk CITEXT NULL,
+    vervaldatum_apk_dt DATE NULL,
+    datum_tenaamstelling_dt DATE NULL,
+    datum_eerste_toelating_dt DATE NULL,
+    datum_eerste_tenaamstelling_in_nederland_dt DATE NULL,
+    vervaldatum_tachograaf_dt DATE NULL,
+    maximum_last_onder_de_vooras_sen_tezamen_koppeling INTEGER NULL,
+    type_remsysteem_voertuig_code CITEXT NULL,
+    rupsonderstelconfiguratiecode CITEXT NULL,
+    wielbasis_voertuig_minimum INTEGER NULL,
+    wielbasis_voertuig_maximum INTEGER NULL,
+    lengte_voertuig_minimum INTEGER NULL,
+    lengte_voertuig_maximum INTEGER NULL,
+    breedte_voertuig_minimum INTEGER NULL,
+    breedte_voertuig_maximum INTEGER NULL,
+    hoogte_voertuig NUMERIC(10, 2) NULL,
+    hoogte_voertuig_minimum NUMERIC(10, 2) NULL,
+    hoogte_voertuig_maximum NUMERIC(10, 2) NULL,
+    massa_bedrijfsklaar_minimaal INTEGER NULL,
+    massa_bedrijfsklaar_maximaal INTEGER NULL,
+    technisch_toelaatbaar_massa_koppelpunt INTEGER NULL,
+    maximum_massa_technisch_maximaal INTEGER NULL,
+    maximum_massa_technisch_minimaal INTEGER NULL,
+    subcategorie_nederland CITEXT NULL,
+    verticale_belasting_koppelpunt_getrokken_voertuig INTEGER NULL,
+    zuinigheidsclassificatie CITEXT NULL,
+    registratie_datum_goedkeuring_afschrijvingsmoment_bpm DATE NULL,
+    registratie_datum_goedkeuring_afschrijvingsmoment_bpm_dt DATE NULL,
+    gem_lading_wrde NUMERIC(10, 2) NULL,
+    aerodyn_voorz CITEXT NULL,
+    massa_alt_aandr INTEGER NULL,
+    verl_cab_ind CITEXT NULL,
+    api_gekentekende_voertuigen_assen CITEXT NULL,
+    api_gekentekende_voertuigen_brandstof CITEXT NULL,
+    api_gekentekende_voertuigen_carrosserie CITEXT NULL,
+    api_gekentekende_voertuigen_carrosserie_specifiek CITEXT NULL,
+    api_gekentekende_voertuigen_voertuigklasse CITEXT NULL,
+    PRIMARY KEY (kenteken)
+);
@@ -0,0 +1,12 @@
+ALTER TABLE voertuigen ADD COLUMN row_hash CHAR(64) NULL, ADD COLUMN snapshot_date DATE NULL, ADD COLUMN removed_at DATE NULL;
+
+CREATE TABLE voertuigen_changes (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY,
+    kenteken CITEXT NOT NULL,
+    change_type TEXT NOT NULL CHECK (change_type IN ('added', 'changed', 'removed')),
+    changed_columns CITEXT NULL,
+    snapshot_date DATE NOT NULL,
+    PRIMARY KEY (id)
+);
+CREATE INDEX idx_voertuigen_changes_kenteken ON voertuigen_changes (kenteken);
+CREATE INDEX idx_voertuigen_changes_snapshot_date ON voertuigen_changes (snapshot_date);
@@ -0,0 +1,11 @@
+CREATE TABLE voertuigen_history (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY,
+    kenteken CITEXT NOT NULL,
+    valid_from DATE NULL,
+    valid_to DATE NULL,
+    row_hash CHAR(64) NOT NULL,
+    changed_columns CITEXT NULL,
+    record JSONB NOT NULL,
+    PRIMARY KEY (id)
+);
+CREATE INDEX idx_voertuigen_history_kenteken ON voertuigen_history (kenteken, valid_to);
@@ -0,0 +1,84 @@
+CREATE TABLE voertuigen_assen (
+    kenteken CITEXT NOT NULL,
+    as_nummer INTEGER NOT NULL,
+    aantal_assen INTEGER NULL,
+    aangedreven_as CITEXT NULL,
+    hefas CITEXT NULL,
+    plaatscode_as CITEXT NULL,
+    spoorbreedte INTEGER NULL,
+    weggedrag_code CITEXT NULL,
+    wettelijk_toegestane_maximum_aslast INTEGER NULL,
+    technisch_toegestane_maximum_aslast INTEGER NULL,
+    afstand_tot_volgende_as_voertuig INTEGER NULL,
+    afstand_tot_volgende_as_voertuig_minimum INTEGER NULL,
+    afstand_tot_volgende_as_voertuig_maximum INTEGER NULL,
+    maximum_last_as_technisch_maximaal INTEGER NULL,
+    maximum_last_as_technisch_minimaal INTEGER NULL,
+    PRIMARY KEY (kenteken, as_nummer)
+);
+
+CREATE TABLE voertuigen_brandstof (
+    kenteken CITEXT NOT NULL,
+    brandstof_volgnummer INTEGER NOT NULL,
+    brandstof_omschrijving CITEXT NULL,
+    brandstofverbruik_buiten NUMERIC(12, 4) NULL,
+    brandstofverbruik_gecombineerd NUMERIC(12, 4) NULL,
+    brandstofverbruik_stad NUMERIC(12, 4) NULL,
+    co2_uitstoot_gecombineerd NUMERIC(12, 4) NULL,
+    co2_uitstoot_gewogen NUMERIC(12, 4) NULL,
+    geluidsniveau_rijdend NUMERIC(12, 4) NULL,
+    geluidsniveau_stationair NUMERIC(12, 4) NULL,
+    emissiecode_omschrijving CITEXT NULL,
+    milieuklasse_eg_goedkeuring_licht CITEXT NULL,
+    milieuklasse_eg_goedkeuring_zwaar CITEXT NULL,
+    uitstoot_deeltjes_licht NUMERIC(12, 4) NULL,
+    uitstoot_deeltjes_zwaar NUMERIC(12, 4) NULL,
+    nettomaximumvermogen NUMERIC(12, 4) NULL,
+    nominaal_continu_maximumvermogen NUMERIC(12, 4) NULL,
+    roetuitstoot NUMERIC(12, 4) NULL,
+    toerental_geluidsniveau NUMERIC(12, 4) NULL,
+    emis_deeltjes_type1_wltp NUMERIC(12, 4) NULL,
+    emissie_co2_gecombineerd_wltp NUMERIC(12, 4) NULL,
+    emis_co2_gewogen_gecombineerd_wltp NUMERIC(12, 4) NULL,
+    brandstof_verbruik_gecombineerd_wltp NUMERIC(12, 4) NULL,
+    brandstof_verbruik_gewogen_gecombineerd_wltp NUMERIC(12, 4) NULL,
+    elektrisch_verbruik_enkel_elektrisch_wltp NUMERIC(12, 4) NULL,
+    actie_radius_enkel_elektrisch_wltp NUMERIC(12, 4) NULL,
+    actie_radius_enkel_elektrisch_stad_wltp NUMERIC(12, 4) NULL,
+    elektrisch_verbruik_extern_opladen_wltp NUMERIC(12, 4) NULL,
+    actie_radius_extern_opladen_wltp NUMERIC(12, 4) NULL,
+    actie_radius_extern_opladen_stad_wltp NUMERIC(12, 4) NULL,
+    max_vermogen_15_minuten NUMERIC(12, 4) NULL,
+    max_vermogen_60_minuten NUMERIC(12, 4) NULL,
+    netto_max_vermogen_elektrisch NUMERIC(12, 4) NULL,
+    klasse_hybride_elektrisch_voertuig CITEXT NULL,
+    opgegeven_maximum_snelheid NUMERIC(12, 4) NULL,
+    uitlaatemissieniveau CITEXT NULL,
+    PRIMARY KEY (kenteken, brandstof_volgnummer)
+);
+
+CREATE TABLE voertuigen_carrosserie (
+    kenteken CITEXT NOT NULL,
+    carrosserie_volgnummer INTEGER NOT NULL,
+    carrosserietype CITEXT NULL,
+    type_carrosserie_europese_omschrijving CITEXT NULL,
+    PRIMARY KEY (kenteken, carrosserie_volgnummer)
+);
+
+CREATE TABLE voertuigen_carrosserie_specifiek (
+    kenteken CITEXT NOT NULL,
+    carrosserie_volgnummer INTEGER NOT NULL,
+    carrosserie_voertuig_nummer_code_volgnummer INTEGER NOT NULL,
+    carrosseriecode CITEXT NULL,
+    carrosserie_voertuig_nummer_europese_omschrijving CITEXT NULL,
+    PRIMARY KEY (kenteken, carrosserie_volgnummer, carrosserie_voertuig_nummer_code_volgnummer)
+);
+
+CREATE TABLE voertuigen_voertuigklasse (
+    kenteken CITEXT NOT NULL,
+    carrosserie_volgnummer INTEGER NOT NULL,
+    carrosserie_klasse_volgnummer INTEGER NOT NULL,
+    voertuigklasse CITEXT NULL,
+    voertuigklasse_omschrijving CITEXT NULL,
+    PRIMARY KEY (kenteken, carrosserie_volgnummer, carrosserie_klasse_volgnummer)
+);
@@ -0,0 +1,28 @@
+CREATE TABLE terugroepacties_status (
+    kenteken CITEXT NOT NULL,
+    referentiecode_rdw CITEXT NOT NULL,
+    code_status CITEXT NULL,
+    status CITEXT NULL,
+    PRIMARY KEY (kenteken, referentiecode_rdw)
+);
+
+CREATE TABLE terugroepacties (
+    referentiecode_rdw CITEXT NOT NULL,
+    referentiecode_fabrikant CITEXT NULL,
+    publicatiedatum_rdw DATE NULL,
+    omschrijving_defect CITEXT NULL,
+    omschrijving_gevolg CITEXT NULL,
+    PRIMARY KEY (referentiecode_rdw)
+);
+
+CREATE TABLE terugroepacties_risico (
+    referentiecode_rdw CITEXT NOT NULL,
+    risico_omschrijving CITEXT NULL
+);
+CREATE INDEX idx_terugroepacties_risico_referentiecode_rdw ON terugroepacties_risico (referentiecode_rdw);
+
+CREATE TABLE terugroepacties_herstel (
+    referentiecode_rdw CITEXT NOT NULL,
+    herstel_omschrijving CITEXT NULL
+);
+CREATE INDEX idx_terugroepacties_herstel_referentiecode_rdw ON terugroepacties_herstel (referentiecode_rdw);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS apk_gebreken;
+DROP TABLE IF EXISTS apk_geconstateerde_gebreken;
+DROP TABLE IF EXISTS apk_keuringen;
@@ -0,0 +1,12 @@
+-- After a swap the indexes of voertuigen are copies with other names,
+-- those are left in place.
+
+DROP INDEX IF EXISTS idx_voertuigen_merk;
+DROP INDEX IF EXISTS idx_voertuigen_handelsbenaming;
+DROP INDEX IF EXISTS idx_voertuigen_voertuigsoort;
+DROP INDEX IF EXISTS idx_voertuigen_eerste_kleur;
+DROP INDEX IF EXISTS idx_voertuigen_datum_eerste_toelating;
+DROP INDEX IF EXISTS idx_voertuigen_vervaldatum_apk;
+DROP INDEX IF EXISTS idx_voertuigen_catalogusprijs;
+DROP INDEX IF EXISTS idx_voertuigen_massa_rijklaar;
+DROP INDEX IF EXISTS idx_voertuigen_cilinderinhoud;
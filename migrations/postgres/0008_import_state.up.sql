@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS import_state (
+    target_table TEXT NOT NULL,
+    file_sha256 CHAR(64) NOT NULL,
+    mode TEXT NOT NULL,
+    snapshot_date DATE NOT NULL,
+    byte_offset BIGINT NOT NULL,
+    line_number BIGINT NOT NULL,
+    read_rows BIGINT NOT NULL,
+    rejected_rows BIGINT NOT NULL,
+    failed_rows BIGINT NOT NULL,
+    inserted_rows BIGINT NOT NULL,
+    updated_rows BIGINT NOT NULL,
+    unchanged_rows BIGINT NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (target_table)
+);
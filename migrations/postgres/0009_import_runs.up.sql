@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS import_runs (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY,
+    started_at TIMESTAMP NOT NULL,
+    finished_at TIMESTAMP NULL,
+    source TEXT NOT NULL,
+    source_size BIGINT NULL,
+    source_sha256 CHAR(64) NULL,
+    snapshot_date DATE NOT NULL,
+    mode TEXT NOT NULL,
+    read_rows BIGINT NOT NULL,
+    inserted_rows BIGINT NOT NULL,
+    updated_rows BIGINT NOT NULL,
+    unchanged_rows BIGINT NOT NULL,
+    rejected_rows BIGINT NOT NULL,
+    failed_rows BIGINT NOT NULL,
+    outcome TEXT NOT NULL CHECK (outcome IN ('running', 'succeeded', 'failed')),
+    error TEXT NULL,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_import_runs_outcome ON import_runs (outcome, id);
@@ -0,0 +1,102 @@
+-- The tables of the MySQL schema in SQLite. TEXT columns use NOCASE like the
+-- case insensitive MySQL collation.
+
+CREATE TABLE voertuigen (
+    kenteken TEXT COLLATE NOCASE NOT NULL,
+    voertuigsoort TEXT COLLATE NOCASE NULL,
+    merk TEXT COLLATE NOCASE NULL,
+    handelsbenaming TEXT COLLATE NOCASE NULL,
+    vervaldatum_apk DATE NULL,
+    datum_tenaamstelling DATE NULL,
+    bruto_bpm NUMERIC NULL,
+    inrichting TEXT COLLATE NOCASE NULL,
+    aantal_zitplaatsen INTEGER NULL,
+    eerste_kleur TEXT COLLATE NOCASE NULL,
+    tweede_kleur TEXT COLLATE NOCASE NULL,
+    aantal_cilinders INTEGER NULL,
+    cilinderinhoud INTEGER NULL,
+    massa_ledig_voertuig INTEGER NULL,
+    toegestane_maximum_massa_voertuig INTEGER NULL,
+    massa_rijklaar INTEGER NULL,
+    maximum_massa_trekken_ongeremd INTEGER NULL,
+    maximum_trekken_massa_geremd INTEGER NULL,
+    datum_eerste_toelating DATE NULL,
+    datum_eerste_tenaamstelling_in_nederland DATE NULL,
+    wacht_op_keuren TEXT COLLATE NOCASE NULL,
+    catalogusprijs NUMERIC NULL,
+    wam_verzekerd TEXT COLLATE NOCASE NULL,
+    maximale_constructiesnelheid INTEGER NULL,
+    laadvermogen INTEGER NULL,
+    oplegger_geremd INTEGER NULL,
+    aanhangwagen_autonoom_geremd INTEGER NULL,
+    aanhangwagen_middenas_geremd INTEGER NULL,
+    aantal_staanplaatsen INTEGER NULL,
+    aantal_deuren INTEGER NULL,
+    aantal_wielen INTEGER NULL,
+    afstand_hart_koppeling_tot_achterzijde_voertuig INTEGER NULL,
+    afstand_voorzijde_voertuig_tot_hart_koppeling INTEGER NULL,
+    afwijkende_maximum_snelheid INTEGER NULL,
+    lengte INTEGER NULL,
+    breedte INTEGER NULL,
+    europese_voertuigcategorie TEXT COLLATE NOCASE NULL,
+    europese_voertuigcategorie_toevoeging TEXT COLLATE NOCASE NULL,
+    europese_uitvoeringcategorie_toevoeging TEXT COLLATE NOCASE NULL,
+    plaats_chassisnummer TEXT COLLATE NOCASE NULL,
+    technische_max_massa_voertuig INTEGER NULL,
+    type TEXT COLLATE NOCASE NULL,
+    type_gasinstallatie TEXT COLLATE NOCASE NULL,
+    typegoedkeuringsnummer TEXT COLLATE NOCASE NULL,
+    variant TEXT COLLATE NOCASE NULL,
+    uitvoering TEXT COLLATE NOCASE NULL,
+    volgnummer_wijziging_eu_typegoedkeuring TEXT COLLATE NOCASE NULL,
+    vermogen_massarijklaar NUMERIC NULL,
+    wielbasis INTEGER NULL,
+    export_indicator TEXT COLLATE NOCASE NULL,
+    openstaande_terugroepactie_indicator TEXT COLLATE NOCASE NULL,
+    vervaldatum_tachograaf DATE NULL,
+    taxi_indicator TEXT COLLATE NOCASE NULL,
+    maximum_massa_samenstelling INTEGER NULL,
+    aantal_rolstoelplaatsen INTEGER NULL,
+    maximum_ondersteunende_snelheid INTEGER NULL,
+    jaar_laatste_registratie_tellerstand INTEGER NULL,
+    tellerstandoordeel TEXT COLLATE NOCASE NULL,
+    code_toelichting_tellerstandoordeel TEXT COLLATE NOCASE NULL,
+    tenaamstellen_mogelijk TEXT COLLATE NOCASE NULL,
+    vervaldatum_apk_dt DATE NULL,
+    datum_tenaamstelling_dt DATE NULL,
+    datum_eerste_toelating_dt DATE NULL,
+    datum_eerste_tenaamstelling_in_nederland_dt DATE NULL,
+    vervaldatum_tachograaf_dt DATE NULL,
+    maximum_last_onder_de_vooras_sen_tezamen_koppeling INTEGER NULL,
+    type_remsysteem_voertuig_code TEXT COLLATE NOCASE NULL,
+    rupsonderstelconfiguratiecode TEXT COLLATE NOCASE NULL,
+    wielbasis_voertuig_minimum INTEGER NULL,
+    wielbasis_voertuig_maximum INTEGER NULL,
+    lengte_voertuig_minimum INTEGER NULL,
+    lengte_voertuig_maximum INTEGER NULL,
+    breedte_voertuig_minimum INTEGER NULL,
+    breedte_voertuig_maximum INTEGER NULL,
+    hoogte_voertuig NUMERIC NULL,
+    hoogte_voertuig_minimum NUMERIC NULL,
+    hoogte_voertuig_maximum NUMERIC NULL,
+    massa_bedrijfsklaar_minimaal INTEGER NULL,
+    massa_bedrijfsklaar_maximaal INTEGER NULL,
+    technisch_toelaatbaar_massa_koppelpunt INTEGER NULL,
+    maximum_massa_technisch_maximaal INTEGER NULL,
+    maximum_massa_technisch_minimaal INTEGER NULL,
+    subcategorie_nederland TEXT COLLATE NOCASE NULL,
+    verticale_belasting_koppelpunt_getrokken_voertuig INTEGER NULL,
+    zuinigheidsclassificatie TEXT COLLATE NOCASE NULL,
+    registratie_datum_goedkeuring_afschrijvingsmoment_bpm DATE NULL,
+    registratie_datum_goedkeuring_afschrijvingsmoment_bpm_dt DATE NULL,
+    gem_lading_wrde NUMERIC NULL,
+    aerodyn_voorz TEXT COLLATE NOCASE NULL,
+    massa_alt_aandr INTEGER NULL,
+    verl_cab_ind TEXT COLLATE NOCASE NULL,
+    api_gekentekende_voertuigen_assen TEXT COLLATE NOCASE NULL,
+    api_gekentekende_voertuigen_brandstof TEXT COLLATE NOCASE NULL,
+    api_gekentekende_voertuigen_carrosserie TEXT COLLATE NOCASE NULL,
+    api_gekentekende_voertuigen_carrosserie_specifiek TEXT COLLATE NOCASE NULL,
+    api_gekentekende_voertuigen_voertuigklasse TEXT COLLATE NOCASE NULL,
+    PRIMARY KEY (kenteken)
+);
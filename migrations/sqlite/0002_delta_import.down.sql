@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS voertuigen_changes;
+ALTER TABLE voertuigen DROP COLUMN removed_at;
+ALTER TABLE voertuigen DROP COLUMN snapshot_date;
+ALTER TABLE voertuigen DROP COLUMN row_hash;
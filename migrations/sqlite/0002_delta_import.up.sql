@@ -0,0 +1,13 @@
+ALTER TABLE voertuigen ADD COLUMN row_hash TEXT COLLATE NOCASE NULL;
+ALTER TABLE voertuigen ADD COLUMN snapshot_date DATE NULL;
+ALTER TABLE voertuigen ADD COLUMN removed_at DATE NULL;
+
+CREATE TABLE voertuigen_changes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    kenteken TEXT COLLATE NOCASE NOT NULL,
+    change_type TEXT NOT NULL CHECK (change_type IN ('added', 'changed', 'removed')),
+    changed_columns TEXT COLLATE NOCASE NULL,
+    snapshot_date DATE NOT NULL
+);
+CREATE INDEX idx_voertuigen_changes_kenteken ON voertuigen_changes (kenteken);
+CREATE INDEX idx_voertuigen_changes_snapshot_date ON voertuigen_changes (snapshot_date);
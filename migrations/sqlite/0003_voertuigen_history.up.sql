@@ -0,0 +1,10 @@
+CREATE TABLE voertuigen_history (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    kenteken TEXT COLLATE NOCASE NOT NULL,
+    valid_from DATE NULL,
+    valid_to DATE NULL,
+    row_hash TEXT NOT NULL,
+    changed_columns TEXT COLLATE NOCASE NULL,
+    record TEXT NOT NULL
+);
+CREATE INDEX idx_voertuigen_history_kenteken ON voertuigen_history (kenteken, valid_to);
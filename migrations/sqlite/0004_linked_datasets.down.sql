@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS voertuigen_voertuigklasse;
+DROP TABLE IF EXISTS voertuigen_carrosserie_specifiek;
+DROP TABLE IF EXISTS voertuigen_carrosserie;
+DROP TABLE IF EXISTS voertuigen_brandstof;
+DROP TABLE IF EXISTS voertuigen_assen;
@@ -0,0 +1,84 @@
+CREATE TABLE voertuigen_assen (
+    kenteken TEXT COLLATE NOCASE NOT NULL,
+    as_nummer INTEGER NOT NULL,
+    aantal_assen INTEGER NULL,
+    aangedreven_as TEXT COLLATE NOCASE NULL,
+    hefas TEXT COLLATE NOCASE NULL,
+    plaatscode_as TEXT COLLATE NOCASE NULL,
+    spoorbreedte INTEGER NULL,
+    weggedrag_code TEXT COLLATE NOCASE NULL,
+    wettelijk_toegestane_maximum_aslast INTEGER NULL,
+    technisch_toegestane_maximum_aslast INTEGER NULL,
+    afstand_tot_volgende_as_voertuig INTEGER NULL,
+    afstand_tot_volgende_as_voertuig_minimum INTEGER NULL,
+    afstand_tot_volgende_as_voertuig_maximum INTEGER NULL,
+    maximum_last_as_technisch_maximaal INTEGER NULL,
+    maximum_last_as_technisch_minimaal INTEGER NULL,
+    PRIMARY KEY (kenteken, as_nummer)
+);
+
+CREATE TABLE voertuigen_brandstof (
+    kenteken TEXT COLLATE NOCASE NOT NULL,
+    brandstof_volgnummer INTEGER NOT NULL,
+    brandstof_omschrijving TEXT COLLATE NOCASE NULL,
+    brandstofverbruik_buiten NUMERIC NULL,
+    brandstofverbruik_gecombineerd NUMERIC NULL,
+    brandstofverbruik_stad NUMERIC NULL,
+    co2_uitstoot_gecombineerd NUMERIC NULL,
+    co2_uitstoot_gewogen NUMERIC NULL,
+    geluidsniveau_rijdend NUMERIC NULL,
+    geluidsniveau_stationair NUMERIC NULL,
+    emissiecode_omschrijving TEXT COLLATE NOCASE NULL,
+    milieuklasse_eg_goedkeuring_licht TEXT COLLATE NOCASE NULL,
+    milieuklasse_eg_goedkeuring_zwaar TEXT COLLATE NOCASE NULL,
+    uitstoot_deeltjes_licht NUMERIC NULL,
+    uitstoot_deeltjes_zwaar NUMERIC NULL,
+    nettomaximumvermogen NUMERIC NULL,
+    nominaal_continu_maximumvermogen NUMERIC NULL,
+    roetuitstoot NUMERIC NULL,
+    toerental_geluidsniveau NUMERIC NULL,
+    emis_deeltjes_type1_wltp NUMERIC NULL,
+    emissie_co2_gecombineerd_wltp NUMERIC NULL,
+    emis_co2_gewogen_gecombineerd_wltp NUMERIC NULL,
+    brandstof_verbruik_gecombineerd_wltp NUMERIC NULL,
+    brandstof_verbruik_gewogen_gecombineerd_wltp NUMERIC NULL,
+    elektrisch_verbruik_enkel_elektrisch_wltp NUMERIC NULL,
+    actie_radius_enkel_elektrisch_wltp NUMERIC NULL,
+    actie_radius_enkel_elektrisch_stad_wltp NUMERIC NULL,
+    elektrisch_verbruik_extern_opladen_wltp NUMERIC NULL,
+    actie_radius_extern_opladen_wltp NUMERIC NULL,
+    actie_radius_extern_opladen_stad_wltp NUMERIC NULL,
+    max_vermogen_15_minuten NUMERIC NULL,
+    max_vermogen_60_minuten NUMERIC NULL,
+    netto_max_vermogen_elektrisch NUMERIC NULL,
+    klasse_hybride_elektrisch_voertuig TEXT COLLATE NOCASE NULL,
+    opgegeven_maximum_snelheid NUMERIC NULL,
+    uitlaatemissieniveau TEXT COLLATE NOCASE NULL,
+    PRIMARY KEY (kenteken, brandstof_volgnummer)
+);
+
+CREATE TABLE voertuigen_carrosserie (
+    kenteken TEXT COLLATE NOCASE NOT NULL,
+    carrosserie_volgnummer INTEGER NOT NULL,
+    carrosserietype TEXT COLLATE NOCASE NULL,
+    type_carrosserie_europese_omschrijving TEXT COLLATE NOCASE NULL,
+    PRIMARY KEY (kenteken, carrosserie_volgnummer)
+);
+
+CREATE TABLE voertuigen_carrosserie_specifiek (
+    kenteken TEXT COLLATE NOCASE NOT NULL,
+    carrosserie_volgnummer INTEGER NOT NULL,
+    carrosserie_voertuig_nummer_code_volgnummer INTEGER NOT NULL,
+    carrosseriecode TEXT COLLATE NOCASE NULL,
+    carrosserie_voertuig_nummer_europese_omschrijving TEXT COLLATE NOCASE NULL,
+    PRIMARY KEY (kenteken, carrosserie_volgnummer, carrosserie_voertuig_nummer_code_volgnummer)
+);
+
+CREATE TABLE voertuigen_voertuigklasse (
+    kenteken TEXT COLLATE NOCASE NOT NULL,
+    carrosserie_volgnummer INTEGER NOT NULL,
+    carrosserie_klasse_volgnummer INTEGER NOT NULL,
+    voertuigklasse TEXT COLLATE NOCASE NULL,
+    voertuigklasse_omschrijving TEXT COLLATE NOCASE NULL,
+    PRIMARY KEY (kenteken, carrosserie_volgnummer, carrosserie_klasse_volgnummer)
+);
@@ -0,0 +1,28 @@
+CREATE TABLE terugroepacties_status (
+    kenteken TEXT COLLATE NOCASE NOT NULL,
+    referentiecode_rdw TEXT COLLATE NOCASE NOT NULL,
+    code_status TEXT COLLATE NOCASE NULL,
+    status TEXT COLLATE NOCASE NULL,
+    PRIMARY KEY (kenteken, referentiecode_rdw)
+);
+
+CREATE TABLE terugroepacties (
+    referentiecode_rdw TEXT COLLATE NOCASE NOT NULL,
+    referentiecode_fabrikant TEXT COLLATE NOCASE NULL,
+    publicatiedatum_rdw DATE NULL,
+    omschrijving_defect TEXT COLLATE NOCASE NULL,
+    omschrijving_gevolg TEXT COLLATE NOCASE NULL,
+    PRIMARY KEY (referentiecode_rdw)
+);
+
+CREATE TABLE terugroepacties_risico (
+    referentiecode_rdw TEXT COLLATE NOCASE NOT NULL,
+    risico_omschrijving TEXT COLLATE NOCASE NULL
+);
+CREATE INDEX idx_terugroepacties_risico_referentiecode_rdw ON terugroepacties_risico (referentiecode_rdw);
+
+CREATE TABLE terugroepacties_herstel (
+    referentiecode_rdw TEXT COLLATE NOCASE NOT NULL,
+    herstel_omschrijving TEXT COLLATE NOCASE NULL
+);
+CREATE INDEX idx_terugroepacties_herstel_referentiecode_rdw ON terugroepacties_herstel (referentiecode_rdw);
@@ -0,0 +1,32 @@
+CREATE TABLE apk_keuringen (
+    kenteken TEXT COLLATE NOCASE NOT NULL,
+    meld_datum_door_keuringsinstantie DATE NOT NULL,
+    meld_tijd_door_keuringsinstantie INTEGER NOT NULL,
+    vervaldatum_keuring DATE NULL,
+    soort_erkenning_keuringsinstantie TEXT COLLATE NOCASE NULL,
+    soort_erkenning_omschrijving TEXT COLLATE NOCASE NULL,
+    soort_melding_ki_omschrijving TEXT COLLATE NOCASE NULL,
+    PRIMARY KEY (kenteken, meld_datum_door_keuringsinstantie, meld_tijd_door_keuringsinstantie)
+);
+
+CREATE TABLE apk_geconstateerde_gebreken (
+    kenteken TEXT COLLATE NOCASE NOT NULL,
+    meld_datum_door_keuringsinstantie DATE NOT NULL,
+    meld_tijd_door_keuringsinstantie INTEGER NOT NULL,
+    gebrek_identificatie TEXT COLLATE NOCASE NOT NULL,
+    soort_erkenning_keuringsinstantie TEXT COLLATE NOCASE NULL,
+    soort_erkenning_omschrijving TEXT COLLATE NOCASE NULL,
+    aantal_gebreken_geconstateerd INTEGER NULL,
+    PRIMARY KEY (kenteken, meld_datum_door_keuringsinstantie, meld_tijd_door_keuringsinstantie, gebrek_identificatie)
+);
+CREATE INDEX idx_apk_geconstateerde_gebreken_gebrek ON apk_geconstateerde_gebreken (gebrek_identificatie);
+
+CREATE TABLE apk_gebreken (
+    gebrek_identificatie TEXT COLLATE NOCASE NOT NULL,
+    ingangsdatum_gebrek DATE NULL,
+    einddatum_gebrek DATE NULL,
+    gebrek_paragraaf_nummer TEXT COLLATE NOCASE NULL,
+    gebrek_artikel_nummer TEXT COLLATE NOCASE NULL,
+    gebrek_omschrijving TEXT COLLATE NOCASE NULL,
+    PRIMARY KEY (gebrek_identificatie)
+);
@@ -0,0 +1,9 @@
+CREATE INDEX idx_voertuigen_merk ON voertuigen (merk);
+CREATE INDEX idx_voertuigen_handelsbenaming ON voertuigen (handelsbenaming);
+CREATE INDEX idx_voertuigen_voertuigsoort ON voertuigen (voertuigsoort);
+CREATE INDEX idx_voertuigen_eerste_kleur ON voertuigen (eerste_kleur);
+CREATE INDEX idx_voertuigen_datum_eerste_toelating ON voertuigen (datum_eerste_toelating, kenteken);
+CREATE INDEX idx_voertuigen_vervaldatum_apk ON voertuigen (vervaldatum_apk, kenteken);
+CREATE INDEX idx_voertuigen_catalogusprijs ON voertuigen (catalogusprijs, kenteken);
+CREATE INDEX idx_voertuigen_massa_rijklaar ON voertuigen (massa_rijklaar, kenteken);
+CREATE INDEX idx_voertuigen_cilinderinhoud ON voertuigen (cilinderinhoud, kenteken);
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS import_state (
+    target_table TEXT NOT NULL,
+    file_sha256 TEXT NOT NULL,
+    mode TEXT NOT NULL,
+    snapshot_date DATE NOT NULL,
+    byte_offset INTEGER NOT NULL,
+    line_number INTEGER NOT NULL,
+    read_rows INTEGER NOT NULL,
+    rejected_rows INTEGER NOT NULL,
+    failed_rows INTEGER NOT NULL,
+    inserted_rows INTEGER NOT NULL,
+    updated_rows INTEGER NOT NULL,
+    unchanged_rows INTEGER NOT NULL,
+    updated_at DATETIME NOT NULL,
+    PRIMARY KEY (target_table)
+);
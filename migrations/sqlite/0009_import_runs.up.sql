@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS import_runs (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    started_at DATETIME NOT NULL,
+    finished_at DATETIME NULL,
+    source TEXT NOT NULL,
+    source_size INTEGER NULL,
+    source_sha256 TEXT NULL,
+    snapshot_date DATE NOT NULL,
+    mode TEXT NOT NULL,
+    read_rows INTEGER NOT NULL,
+    inserted_rows INTEGER NOT NULL,
+    updated_rows INTEGER NOT NULL,
+    unchanged_rows INTEGER NOT NULL,
+    rejected_rows INTEGER NOT NULL,
+    failed_rows INTEGER NOT NULL,
+    outcome TEXT NOT NULL CHECK (outcome IN ('running', 'succeeded', 'failed')),
+    error TEXT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_import_runs_outcome ON import_runs (outcome, id);
@@ -0,0 +1 @@
+ALTER TABLE import_runs ADD COLUMN dataset TEXT NOT NULL DEFAULT 'voertuigen';
@@ -0,0 +1,353 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"slices"
+	"time"
+)
+
+const (
+	defaultRowsPerStatement = 500
+
+	// maxPlaceholders is the most placeholders MySQL accepts in one prepared
+	// statement.
+	maxPlaceholders = 65535
+
+	// mysqlMigrationLock is the GET_LOCK name that serializes migrations.
+	mysqlMigrationLock = "kentekens_migrations"
+)
+
+// mysqlStore is the VehicleStore on the MySQL database of migrations/mysql.
+type mysqlStore struct {
+	db *sql.DB
+
+	// rowsPerStatement caps the rows of one multi-row INSERT, 1 writes every
+	// row with its own statement
+	rowsPerStatement int
+	// maxPacket is max_allowed_packet of the server
+	maxPacket int
+}
+
+// opens the MySQL database and the store on top of it, writing
+// INSERT_ROWS_PER_STATEMENT (default 500) rows per statement
+func openMySQLStore() (*mysqlStore, error) {
+	db, err := connectToDB()
+	if err != nil {
+		return nil, err
+	}
+
+	store := &mysqlStore{db: db, rowsPerStatement: defaultRowsPerStatement}
+	if getEnvVar("INSERT_ROWS_PER_STATEMENT") != "" {
+		store.rowsPerStatement = getIntEnvVar("INSERT_ROWS_PER_STATEMENT")
+	}
+	if err := db.QueryRow("SELECT @@max_allowed_packet").Scan(&store.maxPacket); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// BulkWrite keeps voertuigen_history in step with the batch, see
+// writeWithHistory.
+func (s *mysqlStore) BulkWrite(table string, records []RDWRecord, mode string, snapshot time.Time) (writeResult, error) {
+	return writeWithHistory(s.db, table, mode, records, snapshot, func() (writeResult, error) {
+		return s.bulkWrite(table, records, mode, snapshot)
+	})
+}
+
+// bulkWrite writes the batch within a single transaction, with multi-row
+// INSERT statements to save a round trip per row. A statement that fails is
+// written again row by row, so only the failing rows are lost. The affected
+// rows of MySQL count a new snapshot date as an update, so an upsert looks up
+// the stored row hashes first.
+func (s *mysqlStore) bulkWrite(table string, records []RDWRecord, mode string, snapshot time.Time) (writeResult, error) {
+	if mode == "delta" {
+		return writeDelta(s.db, upsertVoertuigSQL(voertuigenTable), records, snapshot)
+	}
+
+	var result writeResult
+	var suffix string
+	stored := map[string]storedVersion{}
+	if mode == "upsert" {
+		suffix = onDuplicateKeyUpdateSQL()
+		kentekens := make([]string, len(records))
+		for i := range records {
+			kentekens[i] = records[i].Kenteken
+		}
+		var err error
+		if stored, err = getStoredVersions(s.db, table, kentekens); err != nil {
+			return result, err
+		}
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return result, err
+	}
+	defer tx.Rollback()
+
+	rows := make([][]any, len(records))
+	for i := range records {
+		rows[i] = voertuigArgs(&records[i], snapshot)
+	}
+	statement := func(rows int) string {
+		return insertVoertuigRowsSQL(table, rows) + suffix
+	}
+	result.failed, err = s.insertRows(tx, statement, rows, func(i int) {
+		result.count(stored, &records[i])
+	})
+	if err != nil {
+		return result, err
+	}
+
+	return result, tx.Commit()
+}
+
+// WriteRows writes a batch of dataset rows within a single transaction, with
+// the multi-row INSERT statements of BulkWrite.
+func (s *mysqlStore) WriteRows(table string, columns []string, rows [][]any) (int64, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	statement := func(rows int) string {
+		return insertRowsSQL(table, columns, rows)
+	}
+	failed, err := s.insertRows(tx, statement, rows, func(int) {})
+	if err != nil {
+		return failed, err
+	}
+	return failed, tx.Commit()
+}
+
+// insertRows writes rows in tx with the statements of statement for a number
+// of rows, split by splitStatements. A statement that fails is written again
+// row by row, so only the failing rows are lost. written is called with the
+// index of every row that was written, the others are logged and counted.
+func (s *mysqlStore) insertRows(tx *sql.Tx, statement func(rows int) string, rows [][]any, written func(i int)) (int64, error) {
+	// the statements are prepared per row count, which is the same for all
+	// but the last statement of a batch
+	statements := map[int]*sql.Stmt{}
+	defer func() {
+		for _, stmt := range statements {
+			stmt.Close()
+		}
+	}()
+	prepare := func(rows int) (*sql.Stmt, error) {
+		if stmt, ok := statements[rows]; ok {
+			return stmt, nil
+		}
+		stmt, err := tx.Prepare(statement(rows))
+		if err != nil {
+			return nil, err
+		}
+		statements[rows] = stmt
+		return stmt, nil
+	}
+
+	var failed int64
+	start := 0
+	for _, chunk := range splitStatements(rows, s.rowsPerStatement, s.maxPacket) {
+		first := start
+		start += len(chunk)
+
+		stmt, err := prepare(len(chunk))
+		if err != nil {
+			return failed, err
+		}
+		_, err = stmt.Exec(slices.Concat(chunk...)...)
+		if err != nil && len(chunk) > 1 {
+			log.Println("Error inserting rows, writing them one by one ", err)
+			single, err := prepare(1)
+			if err != nil {
+				return failed, err
+			}
+			for i, row := range chunk {
+				if _, err := single.Exec(row...); err != nil {
+					log.Println("Error inserting record ", err)
+					failed++
+					continue
+				}
+				written(first + i)
+			}
+			continue
+		}
+		if err != nil {
+			log.Println("Error inserting record ", err)
+			failed++
+			continue
+		}
+		for i := range chunk {
+			written(first + i)
+		}
+	}
+	return failed, nil
+}
+
+// splitStatements divides rows into the rows of consecutive INSERT statements:
+// at most rowsPerStatement rows, no more than maxPlaceholders placeholders and
+// an estimated size within three quarters of maxPacket, leaving room for the
+// error in the estimate.
+func splitStatements(rows [][]any, rowsPerStatement, maxPacket int) [][][]any {
+	if len(rows) == 0 {
+		return nil
+	}
+	maxRows := min(max(rowsPerStatement, 1), maxPlaceholders/len(rows[0]))
+	maxBytes := maxPacket / 4 * 3
+
+	var statements [][][]any
+	start, size := 0, 0
+	for i, row := range rows {
+		rowSize := argsSize(row)
+		if i > start && (i-start == maxRows || size+rowSize > maxBytes) {
+			statements = append(statements, rows[start:i])
+			start, size = i, 0
+		}
+		size += rowSize
+	}
+	return append(statements, rows[start:])
+}
+
+// argsSize estimates the bytes the arguments of a row take in the execute
+// packet: a type and length per value plus the value itself.
+func argsSize(row []any) int {
+	size := 0
+	for _, value := range row {
+		size += 10
+		switch value := value.(type) {
+		case *string:
+			size += len(*value)
+		case string:
+			size += len(value)
+		default:
+			size += 8
+		}
+	}
+	return size
+}
+
+func (s *mysqlStore) Get(kenteken string, columns projection) (RDWRecord, error) {
+	return getVoertuig(s.db, kenteken, columns)
+}
+
+func (s *mysqlStore) GetMany(kentekens []string, columns projection) (map[string]RDWRecord, error) {
+	return getVoertuigen(s.db, kentekens, columns)
+}
+
+func (s *mysqlStore) Search(q *searchQuery) ([]RDWRecord, string, error) {
+	return searchVoertuigen(s.db, q)
+}
+
+func (s *mysqlStore) Stats() (vehicleStats, error) {
+	return getVehicleStats(s.db)
+}
+
+func (s *mysqlStore) DatasetRows(dataset rdwDataset, column, value string) ([]map[string]any, error) {
+	return getDatasetRows(s.db, dataset, column, value)
+}
+
+func (s *mysqlStore) History(kenteken string) ([]historyVersion, error) {
+	return getVoertuigHistory(s.db, kenteken)
+}
+
+func (s *mysqlStore) Recalls(kenteken string) (voertuigRecalls, error) {
+	return getVoertuigRecalls(s.db, kenteken)
+}
+
+func (s *mysqlStore) Keuringen(kenteken string) ([]keuring, error) {
+	return getVoertuigKeuringen(s.db, kenteken)
+}
+
+func (s *mysqlStore) Soda(q *sodaQuery) ([]map[string]string, error) {
+	return querySoda(s.db, q)
+}
+
+func (s *mysqlStore) ImportRuns(limit int) ([]importRun, error) {
+	return getImportRuns(s.db, limit)
+}
+
+func (s *mysqlStore) LastSucceededImportRun() (importRun, error) {
+	return getLastSucceededImportRun(s.db)
+}
+
+func (s *mysqlStore) MarkRemoved(snapshot time.Time, seen []string) (int64, error) {
+	return markRemoved(s.db, snapshot, seen)
+}
+
+func (s *mysqlStore) ImportState(table string) (importState, error) {
+	return getImportState(s.db, table)
+}
+
+func (s *mysqlStore) SaveImportState(state importState) error {
+	return saveImportState(s.db, state)
+}
+
+func (s *mysqlStore) DeleteImportState(table string) error {
+	return deleteImportState(s.db, table)
+}
+
+func (s *mysqlStore) StartImportRun(run importRun) (int64, error) {
+	return insertImportRun(s.db, run)
+}
+
+func (s *mysqlStore) FinishImportRun(run importRun) error {
+	return finishImportRun(s.db, run)
+}
+
+func (s *mysqlStore) DropStaging(staging string) error {
+	_, err := s.db.Exec("DROP TABLE IF EXISTS " + staging)
+	return err
+}
+
+func (s *mysqlStore) Close() error {
+	return s.db.Close()
+}
+
+func (s *mysqlStore) CreateStaging(table string) (string, error) {
+	return createStagingTable(s.db, table)
+}
+
+func (s *mysqlStore) ValidateStaging(table, staging string, written int64, required []string) error {
+	return validateStagingTable(s.db, table, staging, written, required)
+}
+
+func (s *mysqlStore) SwapStaging(table, staging string) (string, error) {
+	return swapStagingTable(s.db, table, staging)
+}
+
+func (s *mysqlStore) Rollback(table string) (string, string, error) {
+	return rollbackTable(s.db, table)
+}
+
+func (s *mysqlStore) MigrationsDir() string {
+	return "mysql"
+}
+
+// LockMigrations takes a named lock, which MySQL also releases when conn is
+// closed.
+func (s *mysqlStore) LockMigrations(ctx context.Context, conn *sql.Conn) (func(commit bool) error, error) {
+	var locked sql.NullInt64
+	err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", mysqlMigrationLock, int(migrationLockTimeout.Seconds())).Scan(&locked)
+	if err != nil {
+		return nil, err
+	}
+	if locked.Int64 != 1 {
+		return nil, errMigrationLocked
+	}
+	return func(bool) error {
+		_, err := conn.ExecContext(context.Background(), "SELECT RELEASE_LOCK(?)", mysqlMigrationLock)
+		return err
+	}, nil
+}
+
+// TransactionalDDL is false, MySQL commits every CREATE and ALTER right away.
+func (s *mysqlStore) TransactionalDDL() bool {
+	return false
+}
+
+func (s *mysqlStore) Conn(ctx context.Context) (*sql.Conn, error) {
+	return s.db.Conn(ctx)
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+// statementSizes returns the number of rows of every statement.
+func statementSizes(statements [][][]any) []int {
+	sizes := make([]int, len(statements))
+	for i, statement := range statements {
+		sizes[i] = len(statement)
+	}
+	return sizes
+}
+
+func TestSplitStatements(t *testing.T) {
+	row := func(columns int) []any {
+		return make([]any, columns)
+	}
+	rows := func(n, columns int) [][]any {
+		list := make([][]any, n)
+		for i := range list {
+			list[i] = row(columns)
+		}
+		return list
+	}
+
+	tests := []struct {
+		name             string
+		rows             [][]any
+		rowsPerStatement int
+		maxPacket        int
+		want             []int
+	}{
+		{"empty", nil, 500, 1 << 20, []int{}},
+		{"rows per statement", rows(1100, 10), 500, 1 << 30, []int{500, 500, 100}},
+		{"one row per statement", rows(3, 10), 1, 1 << 30, []int{1, 1, 1}},
+		{"zero is one", rows(2, 10), 0, 1 << 30, []int{1, 1}},
+		{"placeholders", rows(1000, 100), 1000, 1 << 30, []int{655, 345}},
+		// 10 values of 18 estimated bytes per row, 3/4 of 1800 fits 7 rows
+		{"packet", rows(20, 10), 500, 1800, []int{7, 7, 6}},
+		{"row larger than packet", rows(2, 10), 500, 10, []int{1, 1}},
+	}
+	for _, test := range tests {
+		got := statementSizes(splitStatements(test.rows, test.rowsPerStatement, test.maxPacket))
+		if !slices.Equal(got, test.want) {
+			t.Errorf("%s: got statements of %v rows, want %v", test.name, got, test.want)
+		}
+	}
+}
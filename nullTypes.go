@@ -0,0 +1,68 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// NullInt is an integer column that may be NULL. It is written to the
+// database as NULL and encoded as null in JSON.
+type NullInt struct {
+	sql.NullInt64
+}
+
+func (n NullInt) MarshalJSON() ([]byte, error) {
+	if !n.Valid {
+		return []byte("null"), nil
+	}
+	return json.Marshal(n.Int64)
+}
+
+// NullDecimal is a DECIMAL column that may be NULL.
+type NullDecimal struct {
+	sql.NullFloat64
+}
+
+func (n NullDecimal) MarshalJSON() ([]byte, error) {
+	if !n.Valid {
+		return []byte("null"), nil
+	}
+	return json.Marshal(n.Float64)
+}
+
+// NullDate is a DATE column that may be NULL, encoded as "2006-01-02" in JSON.
+type NullDate struct {
+	sql.NullTime
+}
+
+// sqliteDateLayouts are the forms in which SQLite returns dates as text, for
+// expressions like MAX(snapshot_date) that have no declared column type.
+var sqliteDateLayouts = []string{"2006-01-02 15:04:05.999999999-07:00", time.RFC3339Nano, time.DateTime, time.DateOnly}
+
+func (n *NullDate) Scan(value any) error {
+	var text string
+	switch value := value.(type) {
+	case string:
+		text = value
+	case []byte:
+		text = string(value)
+	default:
+		return n.NullTime.Scan(value)
+	}
+	for _, layout := range sqliteDateLayouts {
+		if t, err := time.Parse(layout, text); err == nil {
+			n.Time, n.Valid = t, true
+			return nil
+		}
+	}
+	return fmt.Errorf("cannot scan %q into a date", text)
+}
+
+func (n NullDate) MarshalJSON() ([]byte, error) {
+	if !n.Valid {
+		return []byte("null"), nil
+	}
+	return json.Marshal(n.Time.Format(time.DateOnly))
+}
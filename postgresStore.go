@@ -0,0 +1,447 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+// postgresStore is the VehicleStore on PostgreSQL. Full imports are loaded
+// with COPY, the shared queries run unchanged through postgresConn.
+type postgresStore struct {
+	db *sql.DB
+	transactionalSwap
+}
+
+// opens the PostgreSQL database from the DB_* variables, applying the
+// migrations when the database is empty
+func openPostgresStore() (*postgresStore, error) {
+	config, err := pgx.ParseConfig(postgresURL())
+	if err != nil {
+		return nil, err
+	}
+	open := func() (*sql.DB, error) {
+		db := sql.OpenDB(postgresConnector{stdlib.GetConnector(*config, stdlib.OptionAfterConnect(registerCitext))})
+		if err := db.Ping(); err != nil {
+			return nil, err
+		}
+		// PostgreSQL allows 100 connections by default, stay well below that
+		db.SetMaxOpenConns(20)
+		db.SetMaxIdleConns(20)
+		return db, nil
+	}
+
+	db, err := open()
+	if err != nil {
+		return nil, err
+	}
+	var exists bool
+	if err := db.QueryRow("SELECT to_regclass(?) IS NOT NULL OR to_regclass('schema_version') IS NOT NULL", voertuigenTable).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		log.Printf("Creating schema in %s", dbName)
+		if _, err := migrateUp(&postgresStore{db: db}); err != nil {
+			return nil, err
+		}
+		// the open connections were made before citext existed, reconnect
+		// so they know the type
+		db.Close()
+		if db, err = open(); err != nil {
+			return nil, err
+		}
+	}
+	return &postgresStore{db: db, transactionalSwap: transactionalSwap{db, postgresListTables}}, nil
+}
+
+func postgresURL() string {
+	u := url.URL{Scheme: "postgres", User: url.UserPassword(dbUser, dbPass), Host: dbHost, Path: "/" + dbName}
+	if dbPort != "" {
+		u.Host += ":" + dbPort
+	}
+	return u.String()
+}
+
+// registerCitext teaches a new connection that citext values are text, which
+// COPY needs to encode them.
+func registerCitext(ctx context.Context, conn *pgx.Conn) error {
+	var oid uint32
+	err := conn.QueryRow(ctx, "SELECT oid FROM pg_type WHERE typname = 'citext'").Scan(&oid)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	conn.TypeMap().RegisterType(&pgtype.Type{Name: "citext", OID: oid, Codec: pgtype.TextCodec{}})
+	return nil
+}
+
+// postgresConnector hands out postgresConns.
+type postgresConnector struct {
+	driver.Connector
+}
+
+func (c postgresConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &postgresConn{conn.(*stdlib.Conn)}, nil
+}
+
+// postgresConn is a pgx connection that accepts the ? placeholders of the
+// queries shared with MySQL and SQLite.
+type postgresConn struct {
+	*stdlib.Conn
+}
+
+func (c *postgresConn) Prepare(query string) (driver.Stmt, error) {
+	return c.Conn.Prepare(rebind(query))
+}
+
+func (c *postgresConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	return c.Conn.PrepareContext(ctx, rebind(query))
+}
+
+func (c *postgresConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	return c.Conn.ExecContext(ctx, rebind(query), args)
+}
+
+func (c *postgresConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return c.Conn.QueryContext(ctx, rebind(query), args)
+}
+
+// rebind numbers the ? placeholders of query as $1, $2, ... Question marks
+// within string literals are left alone.
+func rebind(query string) string {
+	if !strings.Contains(query, "?") {
+		return query
+	}
+
+	var b strings.Builder
+	n, quoted := 0, false
+	for i := 0; i < len(query); i++ {
+		switch {
+		case query[i] == '\'':
+			quoted = !quoted
+		case query[i] == '?' && !quoted:
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteByte(query[i])
+	}
+	return b.String()
+}
+
+// postgresUpsertSQL is the ON CONFLICT clause of an upsert into table that
+// leaves unchanged vehicles alone, so RETURNING only returns the inserted and
+// updated rows. inserted is true when there was no previous row version. Only
+// the row hash and removal decide, the snapshot date of unchanged vehicles is
+// set afterwards.
+func postgresUpsertSQL(table string) string {
+	return onConflictUpdateSQL() +
+		" WHERE " + table + ".row_hash IS DISTINCT FROM excluded.row_hash" +
+		" OR " + table + ".removed_at IS NOT NULL" +
+		" RETURNING xmax = 0 AS inserted"
+}
+
+// BulkWrite keeps voertuigen_history in step with the batch, see
+// writeWithHistory.
+func (s *postgresStore) BulkWrite(table string, records []RDWRecord, mode string, snapshot time.Time) (writeResult, error) {
+	return writeWithHistory(s.db, table, mode, records, snapshot, func() (writeResult, error) {
+		return s.bulkWrite(table, records, mode, snapshot)
+	})
+}
+
+// bulkWrite loads the batch with COPY in a single transaction. An upsert is
+// copied into a temporary table and merged from there. A batch that fails,
+// for example on a duplicate kenteken, is written again row by row so only
+// the failing rows are lost.
+func (s *postgresStore) bulkWrite(table string, records []RDWRecord, mode string, snapshot time.Time) (writeResult, error) {
+	if mode == "delta" {
+		return writeDelta(s.db, insertVoertuigSQL(voertuigenTable)+onConflictUpdateSQL(), records, snapshot)
+	}
+
+	rows := make([][]any, len(records))
+	for i := range records {
+		rows[i] = voertuigArgs(&records[i], snapshot)
+	}
+	columns := voertuigInsertColumns()
+
+	var result writeResult
+	err := s.inTx(func(ctx context.Context, tx pgx.Tx) error {
+		if mode != "upsert" {
+			var err error
+			result.inserted, err = copyRows(ctx, tx, table, columns, rows)
+			return err
+		}
+
+		const source = "voertuigen_upsert"
+		if _, err := tx.Exec(ctx, "CREATE TEMPORARY TABLE "+source+" (LIKE "+table+") ON COMMIT DROP"); err != nil {
+			return err
+		}
+		if _, err := copyRows(ctx, tx, source, columns, rows); err != nil {
+			return err
+		}
+		list := strings.Join(columns, ", ")
+		merged, err := tx.Query(ctx, "INSERT INTO "+table+" ("+list+") SELECT "+list+" FROM "+source+postgresUpsertSQL(table))
+		if err != nil {
+			return err
+		}
+		defer merged.Close()
+		for merged.Next() {
+			var inserted bool
+			if err := merged.Scan(&inserted); err != nil {
+				return err
+			}
+			if inserted {
+				result.inserted++
+			} else {
+				result.updated++
+			}
+		}
+		if err := merged.Err(); err != nil {
+			return err
+		}
+		merged.Close()
+		result.unchanged = int64(len(rows)) - result.inserted - result.updated
+		_, err = tx.Exec(ctx, "UPDATE "+table+" SET snapshot_date = "+source+".snapshot_date FROM "+source+
+			" WHERE "+table+".kenteken = "+source+".kenteken AND "+table+".snapshot_date IS DISTINCT FROM "+source+".snapshot_date")
+		return err
+	})
+	if err == nil {
+		return result, nil
+	}
+	log.Println("Error copying batch, writing its rows one by one ", err)
+
+	result = writeResult{}
+	query := insertVoertuigSQL(table) + " RETURNING true"
+	if mode == "upsert" {
+		query = insertVoertuigSQL(table) + postgresUpsertSQL(table)
+	}
+	var unchanged []string
+	for i, row := range rows {
+		var inserted bool
+		err := s.db.QueryRow(query, row...).Scan(&inserted)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			unchanged = append(unchanged, records[i].Kenteken)
+			result.unchanged++
+		case err != nil:
+			log.Println("Error inserting record ", err)
+			result.failed++
+		case inserted:
+			result.inserted++
+		default:
+			result.updated++
+		}
+	}
+	return result, touchSnapshot(s.db, table, snapshot, unchanged)
+}
+
+// WriteRows loads a batch of dataset rows with COPY. A batch that fails is
+// inserted again row by row.
+func (s *postgresStore) WriteRows(table string, columns []string, rows [][]any) (int64, error) {
+	err := s.inTx(func(ctx context.Context, tx pgx.Tx) error {
+		_, err := copyRows(ctx, tx, table, columns, rows)
+		return err
+	})
+	if err == nil {
+		return 0, nil
+	}
+	log.Println("Error copying batch, writing its rows one by one ", err)
+
+	var failed int64
+	query := insertRowsSQL(table, columns, 1)
+	for _, row := range rows {
+		if _, err := s.db.Exec(query, row...); err != nil {
+			log.Println("Error inserting record ", err)
+			failed++
+		}
+	}
+	return failed, nil
+}
+
+// inTx runs f in a transaction on the pgx connection underneath one of the
+// pool, for COPY which database/sql does not offer.
+func (s *postgresStore) inTx(f func(ctx context.Context, tx pgx.Tx) error) error {
+	ctx := context.Background()
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	return conn.Raw(func(driverConn any) error {
+		tx, err := driverConn.(*postgresConn).Conn.Conn().Begin(ctx)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback(ctx)
+
+		if err := f(ctx, tx); err != nil {
+			return err
+		}
+		return tx.Commit(ctx)
+	})
+}
+
+// copyRows copies rows into table. The values are resolved to plain Go types
+// first, COPY encodes them in the binary format of the column types.
+func copyRows(ctx context.Context, tx pgx.Tx, table string, columns []string, rows [][]any) (int64, error) {
+	source := pgx.CopyFromSlice(len(rows), func(i int) ([]any, error) {
+		values := make([]any, len(rows[i]))
+		for j, value := range rows[i] {
+			if valuer, ok := value.(driver.Valuer); ok {
+				var err error
+				if value, err = valuer.Value(); err != nil {
+					return nil, err
+				}
+			}
+			values[j] = value
+		}
+		return values, nil
+	})
+	return tx.CopyFrom(ctx, pgx.Identifier{table}, columns, source)
+}
+
+func (s *postgresStore) Get(kenteken string, columns projection) (RDWRecord, error) {
+	return getVoertuig(s.db, kenteken, columns)
+}
+
+func (s *postgresStore) GetMany(kentekens []string, columns projection) (map[string]RDWRecord, error) {
+	return getVoertuigen(s.db, kentekens, columns)
+}
+
+func (s *postgresStore) Search(q *searchQuery) ([]RDWRecord, string, error) {
+	return searchVoertuigen(s.db, q)
+}
+
+func (s *postgresStore) Stats() (vehicleStats, error) {
+	return getVehicleStats(s.db)
+}
+
+func (s *postgresStore) DatasetRows(dataset rdwDataset, column, value string) ([]map[string]any, error) {
+	return getDatasetRows(s.db, dataset, column, value)
+}
+
+func (s *postgresStore) History(kenteken string) ([]historyVersion, error) {
+	return getVoertuigHistory(s.db, kenteken)
+}
+
+func (s *postgresStore) Recalls(kenteken string) (voertuigRecalls, error) {
+	return getVoertuigRecalls(s.db, kenteken)
+}
+
+func (s *postgresStore) Keuringen(kenteken string) ([]keuring, error) {
+	return getVoertuigKeuringen(s.db, kenteken)
+}
+
+func (s *postgresStore) Soda(q *sodaQuery) ([]map[string]string, error) {
+	return querySoda(s.db, q)
+}
+
+func (s *postgresStore) ImportRuns(limit int) ([]importRun, error) {
+	return getImportRuns(s.db, limit)
+}
+
+func (s *postgresStore) LastSucceededImportRun() (importRun, error) {
+	return getLastSucceededImportRun(s.db)
+}
+
+func (s *postgresStore) MarkRemoved(snapshot time.Time, seen []string) (int64, error) {
+	return markRemoved(s.db, snapshot, seen)
+}
+
+func (s *postgresStore) ImportState(table string) (importState, error) {
+	return getImportState(s.db, table)
+}
+
+func (s *postgresStore) SaveImportState(state importState) error {
+	return saveImportState(s.db, state)
+}
+
+func (s *postgresStore) DeleteImportState(table string) error {
+	return deleteImportState(s.db, table)
+}
+
+func (s *postgresStore) StartImportRun(run importRun) (int64, error) {
+	return insertImportRun(s.db, run)
+}
+
+func (s *postgresStore) FinishImportRun(run importRun) error {
+	return finishImportRun(s.db, run)
+}
+
+func (s *postgresStore) DropStaging(staging string) error {
+	_, err := s.db.Exec("DROP TABLE IF EXISTS " + staging)
+	return err
+}
+
+func (s *postgresStore) Close() error {
+	return s.db.Close()
+}
+
+// CreateStaging recreates the staging table with the columns, constraints and
+// indexes of table. PostgreSQL names the copied indexes after the staging
+// table and numbers them when the name is taken by an earlier swap.
+func (s *postgresStore) CreateStaging(table string) (string, error) {
+	staging := stagingTableName(table)
+	if _, err := s.db.Exec("DROP TABLE IF EXISTS " + staging); err != nil {
+		return "", err
+	}
+	if _, err := s.db.Exec("CREATE TABLE " + staging + " (LIKE " + table + " INCLUDING ALL)"); err != nil {
+		return "", err
+	}
+	return staging, nil
+}
+
+func (s *postgresStore) ValidateStaging(table, staging string, written int64, required []string) error {
+	return validateStagingTable(s.db, table, staging, written, required)
+}
+
+// postgresMigrationLock is the advisory lock key that serializes migrations.
+const postgresMigrationLock = 8243519076
+
+// postgresListTables selects the tables of the current schema with a name LIKE
+// its argument.
+const postgresListTables = "SELECT tablename FROM pg_tables WHERE schemaname = current_schema() AND tablename LIKE ?"
+
+func (s *postgresStore) MigrationsDir() string {
+	return "postgres"
+}
+
+// LockMigrations takes a session level advisory lock on
+// postgresMigrationLock.
+func (s *postgresStore) LockMigrations(ctx context.Context, conn *sql.Conn) (func(commit bool) error, error) {
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock(?)", postgresMigrationLock); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return nil, errMigrationLocked
+		}
+		return nil, err
+	}
+	return func(bool) error {
+		_, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock(?)", postgresMigrationLock)
+		return err
+	}, nil
+}
+
+func (s *postgresStore) TransactionalDDL() bool {
+	return true
+}
+
+func (s *postgresStore) Conn(ctx context.Context) (*sql.Conn, error) {
+	return s.db.Conn(ctx)
+}
@@ -0,0 +1,18 @@
+package main
+
+import "testing"
+
+func TestRebind(t *testing.T) {
+	tests := map[string]string{
+		"SELECT 1":                              "SELECT 1",
+		"SELECT * FROM voertuigen WHERE a = ?":  "SELECT * FROM voertuigen WHERE a = $1",
+		"INSERT INTO t (a, b) VALUES (?, ?)":    "INSERT INTO t (a, b) VALUES ($1, $2)",
+		"SELECT '?' FROM t WHERE a = ? AND b=?": "SELECT '?' FROM t WHERE a = $1 AND b=$2",
+		"SELECT 'it''s?' WHERE a IN (?, ?, ?)":  "SELECT 'it''s?' WHERE a IN ($1, $2, $3)",
+	}
+	for query, want := range tests {
+		if got := rebind(query); got != want {
+			t.Errorf("rebind(%q) = %q, want %q", query, got, want)
+		}
+	}
+}
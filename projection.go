@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// projection is the list of voertuigen columns a query selects and a response
+// contains, in the order of voertuigColumns.
+type projection []voertuigColumn
+
+// kentekenProjection only selects the kenteken, enough to check whether a
+// vehicle exists.
+var kentekenProjection = projectColumns(map[string]bool{"kenteken": true})
+
+// parseProjection parses a fields= parameter, a comma separated list of
+// column names. An empty value selects every column.
+func parseProjection(value string) (projection, error) {
+	if value == "" {
+		return voertuigColumns, nil
+	}
+
+	wanted := make(map[string]bool)
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		if _, ok := findVoertuigColumn(name); !ok {
+			return nil, fmt.Errorf("unknown field %q", name)
+		}
+		wanted[name] = true
+	}
+	return projectColumns(wanted), nil
+}
+
+// with returns the projection extended with the named columns, for columns a
+// query needs itself whether or not the client asked for them.
+func (p projection) with(names ...string) projection {
+	wanted := make(map[string]bool, len(p)+len(names))
+	for _, column := range p {
+		wanted[column.name] = true
+	}
+	for _, name := range names {
+		wanted[name] = true
+	}
+	return projectColumns(wanted)
+}
+
+func projectColumns(wanted map[string]bool) projection {
+	var p projection
+	for _, column := range voertuigColumns {
+		if wanted[column.name] {
+			p = append(p, column)
+		}
+	}
+	return p
+}
+
+func (p projection) columnList() string {
+	names := make([]string, len(p))
+	for i, column := range p {
+		names[i] = column.name
+	}
+	return strings.Join(names, ", ")
+}
+
+// scanDest returns the fields of record for the columns of the projection.
+func (p projection) scanDest(record *RDWRecord) []any {
+	dest := make([]any, len(p))
+	for i, column := range p {
+		dest[i] = column.field(record)
+	}
+	return dest
+}
+
+// projectedRecord is a record that marshals to JSON with only the columns of
+// the projection, in their order.
+type projectedRecord struct {
+	record  RDWRecord
+	columns projection
+}
+
+func (p projection) records(records []RDWRecord) []projectedRecord {
+	projected := make([]projectedRecord, len(records))
+	for i, record := range records {
+		projected[i] = projectedRecord{record: record, columns: p}
+	}
+	return projected
+}
+
+func (r projectedRecord) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, column := range r.columns {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		value, err := json.Marshal(column.field(&r.record))
+		if err != nil {
+			return nil, err
+		}
+		b.WriteString(strconv.Quote(column.name))
+		b.WriteByte(':')
+		b.Write(value)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+// The response formats of the vehicle endpoints.
+const (
+	formatJSON   = "application/json"
+	formatCSV    = "text/csv"
+	formatNDJSON = "application/x-ndjson"
+)
+
+// acceptFormats maps the media types a client can ask for to a format.
+var acceptFormats = map[string]string{
+	"*/*":                  formatJSON,
+	"application/*":        formatJSON,
+	"application/json":     formatJSON,
+	"text/*":               formatCSV,
+	"text/csv":             formatCSV,
+	"application/x-ndjson": formatNDJSON,
+	"application/ndjson":   formatNDJSON,
+}
+
+// recordFormats are the formats of the endpoints that return vehicles, the
+// other endpoints only serve JSON.
+var recordFormats = []string{formatJSON, formatCSV, formatNDJSON}
+
+// negotiateFormat picks the format with the highest q value among supported
+// from an Accept header. Without an Accept header the response is JSON;
+// false means none of the accepted media types can be served.
+func negotiateFormat(accept string, supported []string) (string, bool) {
+	if strings.TrimSpace(accept) == "" {
+		return formatJSON, true
+	}
+
+	best, bestQ := "", 0.0
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, _ := strings.Cut(part, ";")
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			if key, value, ok := strings.Cut(strings.TrimSpace(param), "="); ok && key == "q" {
+				q, _ = strconv.ParseFloat(value, 64)
+			}
+		}
+		format, ok := acceptFormats[strings.ToLower(strings.TrimSpace(mediaType))]
+		if ok && q > bestQ && slices.Contains(supported, format) {
+			best, bestQ = format, q
+		}
+	}
+	return best, best != ""
+}
+
+// writeRecords writes records as CSV, with the column names as header, or as
+// NDJSON, one JSON object per line. NULL values are empty in CSV.
+func writeRecords(w http.ResponseWriter, format string, columns projection, records []RDWRecord) {
+	var err error
+	switch format {
+	case formatCSV:
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		writer := csv.NewWriter(w)
+		header := make([]string, len(columns))
+		for i, column := range columns {
+			header[i] = column.name
+		}
+		writer.Write(header)
+		for _, record := range records {
+			row := make([]string, len(columns))
+			for i, column := range columns {
+				if value, ok := fieldString(column.field(&record)); ok {
+					row[i] = value
+				}
+			}
+			writer.Write(row)
+		}
+		writer.Flush()
+		err = writer.Error()
+	case formatNDJSON:
+		w.Header().Set("Content-Type", formatNDJSON)
+		encoder := json.NewEncoder(w)
+		for _, record := range columns.records(records) {
+			if err = encoder.Encode(record); err != nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		log.Println("Error writing response ", err)
+	}
+}
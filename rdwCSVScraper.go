@@ -1,418 +1,463 @@
 package main
 
 import (
+	"crypto/sha256"
 	"database/sql"
 	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"hash"
 	"io"
 	"log"
 	"os"
-	"sync"
+	"sync/atomic"
 	"time"
 )
 
+// RDWRecord is one row of the Gekentekende_voertuigen dataset. Empty values
+// in the CSV are kept as NULL rather than a zero date or number.
 type RDWRecord struct {
-	Kenteken        string
-	Voertuigsoort   string
-	Merk            string
-	Handelsbenaming string
+	Kenteken        string `json:"kenteken"`
+	Voertuigsoort   string `json:"voertuigsoort"`
+	Merk            string `json:"merk"`
+	Handelsbenaming string `json:"handelsbenaming"`
 
-	VervaldatumApk      time.Time
-	DatumTenaamstelling time.Time
+	VervaldatumApk      NullDate `json:"vervaldatum_apk"`
+	DatumTenaamstelling NullDate `json:"datum_tenaamstelling"`
 
-	BrutoBpm float32
+	BrutoBpm NullDecimal `json:"bruto_bpm"`
 
-	Inrichting        string
-	AantalZitplaatsen int
-	EersteKleur       string
-	TweedeKleur       string
+	Inrichting        string  `json:"inrichting"`
+	AantalZitplaatsen NullInt `json:"aantal_zitplaatsen"`
+	EersteKleur       string  `json:"eerste_kleur"`
+	TweedeKleur       string  `json:"tweede_kleur"`
 
-	AantalCilinders int
-	Cilinderinhoud  int
+	AantalCilinders NullInt `json:"aantal_cilinders"`
+	Cilinderinhoud  NullInt `json:"cilinderinhoud"`
 
-	MassaLedigVoertuig             int
-	ToegestaneMaximumMassaVoertuig int
-	MassaRijklaar                  int
-	MaximumTrekkenMassaOngeremd    int
-	MaximumTrekkenMassaGeremd      int
+	MassaLedigVoertuig             NullInt `json:"massa_ledig_voertuig"`
+	ToegestaneMaximumMassaVoertuig NullInt `json:"toegestane_maximum_massa_voertuig"`
+	MassaRijklaar                  NullInt `json:"massa_rijklaar"`
+	MaximumTrekkenMassaOngeremd    NullInt `json:"maximum_massa_trekken_ongeremd"`
+	MaximumTrekkenMassaGeremd      NullInt `json:"maximum_trekken_massa_geremd"`
 
-	DatumEersteToelating        time.Time
-	DatumEersteTenaamstallingNL time.Time
-	WachtOpKeuren               string
-	Catalogusprijs              float32
-	WamVerzekerd                string
+	DatumEersteToelating        NullDate    `json:"datum_eerste_toelating"`
+	DatumEersteTenaamstallingNL NullDate    `json:"datum_eerste_tenaamstelling_in_nederland"`
+	WachtOpKeuren               string      `json:"wacht_op_keuren"`
+	Catalogusprijs              NullDecimal `json:"catalogusprijs"`
+	WamVerzekerd                string      `json:"wam_verzekerd"`
 
-	MaxSnelheid    int
-	Laadvermogen   int
-	OpleggerGeremd int
+	MaxSnelheid    NullInt `json:"maximale_constructiesnelheid"`
+	Laadvermogen   NullInt `json:"laadvermogen"`
+	OpleggerGeremd NullInt `json:"oplegger_geremd"`
 
-	AanhangwagenAutonoomGeremd int
-	AanhangwagenMiddenasGeremd int
+	AanhangwagenAutonoomGeremd NullInt `json:"aanhangwagen_autonoom_geremd"`
+	AanhangwagenMiddenasGeremd NullInt `json:"aanhangwagen_middenas_geremd"`
 
-	AantalStaanplaatsen int
-	AantalDeuren        int
-	AantalWielen        int
+	AantalStaanplaatsen NullInt `json:"aantal_staanplaatsen"`
+	AantalDeuren        NullInt `json:"aantal_deuren"`
+	AantalWielen        NullInt `json:"aantal_wielen"`
 
-	AfstandHartKoppelingTotAchterzijdeVoertuig int
-	AfstandVoorzijdeVoertuigTotHartKoppeling   int
+	AfstandHartKoppelingTotAchterzijdeVoertuig NullInt `json:"afstand_hart_koppeling_tot_achterzijde_voertuig"`
+	AfstandVoorzijdeVoertuigTotHartKoppeling   NullInt `json:"afstand_voorzijde_voertuig_tot_hart_koppeling"`
 
-	AfwijkendeMaximumSnelheid int
+	AfwijkendeMaximumSnelheid NullInt `json:"afwijkende_maximum_snelheid"`
 
-	Lengte  int
-	Breedte int
+	Lengte  NullInt `json:"lengte"`
+	Breedte NullInt `json:"breedte"`
 
-	EuropeseVoertuigCategorie             string
-	EuropeseVoertuigCategorieToevoeging   string
-	EuropeseUitvoeringcategorieToevoeging string
+	EuropeseVoertuigCategorie             string `json:"europese_voertuigcategorie"`
+	EuropeseVoertuigCategorieToevoeging   string `json:"europese_voertuigcategorie_toevoeging"`
+	EuropeseUitvoeringcategorieToevoeging string `json:"europese_uitvoeringcategorie_toevoeging"`
 
-	PlaatsChassisnummer        string
-	TechnischeMaxMassaVoertuig int
+	PlaatsChassisnummer        string  `json:"plaats_chassisnummer"`
+	TechnischeMaxMassaVoertuig NullInt `json:"technische_max_massa_voertuig"`
 
-	Type                   string
-	TypeGasinstallatie     string
-	Typegoedkeuringsnummer string
-	Variant                string
-	Uitvoering             string
+	Type                   string `json:"type"`
+	TypeGasinstallatie     string `json:"type_gasinstallatie"`
+	Typegoedkeuringsnummer string `json:"typegoedkeuringsnummer"`
+	Variant                string `json:"variant"`
+	Uitvoering             string `json:"uitvoering"`
 
-	VolgnummerWijzigingEuTypegoedkeuring int
-	VermoegenMassarijklaar               float32
-	Wielbasis                            int
+	VolgnummerWijzigingEuTypegoedkeuring NullInt     `json:"volgnummer_wijziging_eu_typegoedkeuring"`
+	VermoegenMassarijklaar               NullDecimal `json:"vermogen_massarijklaar"`
+	Wielbasis                            NullInt     `json:"wielbasis"`
 
-	exportIndicator                    string
-	OpenstaandeTerugroepactieIndicator string
+	ExportIndicator                    string `json:"export_indicator"`
+	OpenstaandeTerugroepactieIndicator string `json:"openstaande_terugroepactie_indicator"`
 
-	VervaldatumTachograaf time.Time
-	TaxiIndicator         string
+	VervaldatumTachograaf NullDate `json:"vervaldatum_tachograaf"`
+	TaxiIndicator         string   `json:"taxi_indicator"`
 
-	MaximumMassaSamenstelling     int
-	AantalRolstoelplaatsen        int
-	MaximumOndersteunendeSnelheid float32
+	MaximumMassaSamenstelling     NullInt `json:"maximum_massa_samenstelling"`
+	AantalRolstoelplaatsen        NullInt `json:"aantal_rolstoelplaatsen"`
+	MaximumOndersteunendeSnelheid NullInt `json:"maximum_ondersteunende_snelheid"`
 
-	JaarLaatsteRegistratieTellerstand int
-	Tellerstandoordeel                string
-	CodeToelichtingTellerstandoordeel string
-	TenaamstellenMogelijk             string
+	JaarLaatsteRegistratieTellerstand NullInt `json:"jaar_laatste_registratie_tellerstand"`
+	Tellerstandoordeel                string  `json:"tellerstandoordeel"`
+	CodeToelichtingTellerstandoordeel string  `json:"code_toelichting_tellerstandoordeel"`
+	TenaamstellenMogelijk             string  `json:"tenaamstellen_mogelijk"`
 
-	VervaldatumApkDt                       time.Time
-	DatumTenaamstellingDt                  time.Time
-	DatumEersteToelatingDt                 time.Time
-	DatumEersteTenaamstellingInNederlandDt time.Time
-	VervaldatumTachograafDt                time.Time
+	VervaldatumApkDt                       NullDate `json:"vervaldatum_apk_dt"`
+	DatumTenaamstellingDt                  NullDate `json:"datum_tenaamstelling_dt"`
+	DatumEersteToelatingDt                 NullDate `json:"datum_eerste_toelating_dt"`
+	DatumEersteTenaamstellingInNederlandDt NullDate `json:"datum_eerste_tenaamstelling_in_nederland_dt"`
+	VervaldatumTachograafDt                NullDate `json:"vervaldatum_tachograaf_dt"`
 
-	MaximumLastOnderDeVoorasSenTezamenKoppeling int
-	TypeRemsysteemVoertuigCode                  string
+	MaximumLastOnderDeVoorasSenTezamenKoppeling NullInt `json:"maximum_last_onder_de_vooras_sen_tezamen_koppeling"`
+	TypeRemsysteemVoertuigCode                  string  `json:"type_remsysteem_voertuig_code"`
 
-	Rupsonderstelconfiguratiecode string
-	WielbasisVoertuigMinimum      int
-	WielbasisVoertuigMaximum      int
+	Rupsonderstelconfiguratiecode string  `json:"rupsonderstelconfiguratiecode"`
+	WielbasisVoertuigMinimum      NullInt `json:"wielbasis_voertuig_minimum"`
+	WielbasisVoertuigMaximum      NullInt `json:"wielbasis_voertuig_maximum"`
 
-	LengteVoertuigMinimum  int
-	LengteVoertuigMaximum  int
-	BreedteVoertuigMinimum int
-	BreedteVoertuigMaximum int
+	LengteVoertuigMinimum  NullInt `json:"lengte_voertuig_minimum"`
+	LengteVoertuigMaximum  NullInt `json:"lengte_voertuig_maximum"`
+	BreedteVoertuigMinimum NullInt `json:"breedte_voertuig_minimum"`
+	BreedteVoertuigMaximum NullInt `json:"breedte_voertuig_maximum"`
 
-	HoogteVoertuig        float32
-	HoogteVoertuigMinimum float32
-	HoogteVoertuigMaximum float32
+	HoogteVoertuig        NullDecimal `json:"hoogte_voertuig"`
+	HoogteVoertuigMinimum NullDecimal `json:"hoogte_voertuig_minimum"`
+	HoogteVoertuigMaximum NullDecimal `json:"hoogte_voertuig_maximum"`
 
-	MassaBedrijfsklaarMinimaal int
-	MassaBedrijfsklaarMaximaal int
+	MassaBedrijfsklaarMinimaal NullInt `json:"massa_bedrijfsklaar_minimaal"`
+	MassaBedrijfsklaarMaximaal NullInt `json:"massa_bedrijfsklaar_maximaal"`
 
-	TechnischToelaatbaarMassaKoppelpunt int
-	MaximumMassaTechnischMaximaal       int
-	MaximumMassaTechnischMinimaal       int
+	TechnischToelaatbaarMassaKoppelpunt NullInt `json:"technisch_toelaatbaar_massa_koppelpunt"`
+	MaximumMassaTechnischMaximaal       NullInt `json:"maximum_massa_technisch_maximaal"`
+	MaximumMassaTechnischMinimaal       NullInt `json:"maximum_massa_technisch_minimaal"`
 
-	SubcategorieNederland                         string
-	VerticaleBelastingKoppelpuntGetrokkenVoertuig int
-	Zuinigheidsclassificatie                      string
+	SubcategorieNederland                         string  `json:"subcategorie_nederland"`
+	VerticaleBelastingKoppelpuntGetrokkenVoertuig NullInt `json:"verticale_belasting_koppelpunt_getrokken_voertuig"`
+	Zuinigheidsclassificatie                      string  `json:"zuinigheidsclassificatie"`
 
-	RegistratieDatumGoedkeuringAfschrijvingsmomentBpm   time.Time
-	RegistratieDatumGoedkeuringAfschrijvingsmomentBpmDt time.Time
+	RegistratieDatumGoedkeuringAfschrijvingsmomentBpm   NullDate `json:"registratie_datum_goedkeuring_afschrijvingsmoment_bpm"`
+	RegistratieDatumGoedkeuringAfschrijvingsmomentBpmDt NullDate `json:"registratie_datum_goedkeuring_afschrijvingsmoment_bpm_dt"`
 
-	GemLadingWrde float32
-	AerodynVoorz  string
-	MassaAltAandr int
-	VerlCabInd    string
+	GemLadingWrde NullDecimal `json:"gem_lading_wrde"`
+	AerodynVoorz  string      `json:"aerodyn_voorz"`
+	MassaAltAandr NullInt     `json:"massa_alt_aandr"`
+	VerlCabInd    string      `json:"verl_cab_ind"`
 
-	ApiGekentekendeVoertuigenAssen                string
-	ApiGekentekendeVoertuigenBrandstof            string
-	ApiGekentekendeVoertuigenCarrosserie          string
-	ApiGekentekendeVoertuigenCarrosserieSpecifiek string
-	ApiGekentekendeVoertuigenVoertuigklasse       string
+	ApiGekentekendeVoertuigenAssen                string `json:"api_gekentekende_voertuigen_assen"`
+	ApiGekentekendeVoertuigenBrandstof            string `json:"api_gekentekende_voertuigen_brandstof"`
+	ApiGekentekendeVoertuigenCarrosserie          string `json:"api_gekentekende_voertuigen_carrosserie"`
+	ApiGekentekendeVoertuigenCarrosserieSpecifiek string `json:"api_gekentekende_voertuigen_carrosserie_specifiek"`
+	ApiGekentekendeVoertuigenVoertuigklasse       string `json:"api_gekentekende_voertuigen_voertuigklasse"`
 }
 
-func main() {
+const (
+	defaultCSVFile         = "rdw-1m.csv"
+	defaultImportBatchSize = 2000
+)
+
+// importStats counts rows over all batches of one import run.
+type importStats struct {
+	read      atomic.Int64
+	rejected  atomic.Int64
+	failed    atomic.Int64
+	inserted  atomic.Int64
+	updated   atomic.Int64
+	unchanged atomic.Int64
+}
+
+// add counts the result of one batch.
+func (stats *importStats) add(result writeResult) {
+	stats.failed.Add(result.failed)
+	stats.inserted.Add(result.inserted)
+	stats.updated.Add(result.updated)
+	stats.unchanged.Add(result.unchanged)
+}
+
+// recordLoader is implemented by stores with a bulk loader that is faster
+// than BulkWrite for filling a staging table, like LOAD DATA on MySQL.
+// LoadRecords loads the records until the channel is closed and returns how
+// many rows were loaded.
+type recordLoader interface {
+	LoadRecords(table string, records <-chan RDWRecord, snapshot time.Time) (int64, error)
+}
+
+// importBatchSize returns the number of records written per transaction,
+// IMPORT_BATCH_SIZE (default 2000).
+func importBatchSize() int {
+	if getEnvVar("IMPORT_BATCH_SIZE") != "" {
+		return getIntEnvVar("IMPORT_BATCH_SIZE")
+	}
+	return defaultImportBatchSize
+}
+
+// runImport reads the RDW CSV and inserts every record into the voertuigen table.
+// The CSV is read from -file (default CSV_FILE or rdw-1m.csv), or streamed
+// straight from the RDW open data portal with --from-url.
+//
+// In the default swap mode the records go into a staging table that replaces
+// voertuigen once it passes validation, so readers never see a half loaded
+// table. The insert mode writes straight into the live table, the upsert mode
+// does the same but updates vehicles that already exist. The delta mode only
+// writes what changed since the previous snapshot, see writeDelta.
+//
+// A -file import outside the delta mode records the position up to which its
+// batches are committed in import_state. When it dies halfway, --resume
+// continues the same file from that position instead of starting over.
+// Every run, and how it ended, is recorded in import_runs. Once the delta
+// mode has started the history, the other modes keep it in step.
+func runImport(args []string) {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	path := flags.String("file", getEnvVar("CSV_FILE"), "path of the RDW CSV to import")
+	fromURL := flags.Bool("from-url", false, "download the CSV from the RDW open data portal instead of reading a file")
+	mode := flags.String("mode", "swap", "import mode: swap (full re-import via a staging table), insert (into the live table), upsert (insert or update in the live table) or delta (record changes since the previous snapshot)")
+	rejectsPath := flags.String("rejects", defaultRejectsFile, "CSV file receiving the rows that could not be converted")
+	maxErrors := flags.Int("max-errors", defaultMaxErrors, "number of rejected rows after which the import is aborted")
+	snapshotDate := flags.String("snapshot-date", time.Now().Format(time.DateOnly), "date of the RDW snapshot being imported")
+	loaderName := flags.String("loader", "insert", "how a swap import fills the staging table: insert (batched INSERT statements) or load-data (one LOAD DATA LOCAL INFILE stream, MySQL only)")
+	resume := flags.Bool("resume", false, "continue the unfinished import of the same -file from its last checkpoint")
+	flags.Parse(args)
+
+	if *mode != "swap" && *mode != "insert" && *mode != "upsert" && *mode != "delta" {
+		log.Fatalf("Unknown import mode %q", *mode)
+	}
+	if *loaderName != "insert" && *loaderName != "load-data" {
+		log.Fatalf("Unknown loader %q", *loaderName)
+	}
+	if *loaderName == "load-data" && *mode != "swap" {
+		log.Fatal("-loader load-data only loads the staging table of -mode swap")
+	}
+	// checkpoints need a file to seek in and batches that can be written
+	// again, which the LOAD DATA stream and the delta mode are not
+	checkpointed := !*fromURL && *loaderName == "insert" && *mode != "delta"
+	if *resume && !checkpointed {
+		log.Fatal("--resume only continues -file imports with -loader insert and a mode other than delta")
+	}
+	snapshot, err := time.Parse(time.DateOnly, *snapshotDate)
+	if err != nil {
+		log.Fatal("Error parsing snapshot date ", err)
+	}
+
 	defer timeTrack(time.Now(), "CSV processing")
 	log.Println("Starting CSV processing")
 
-	file, err := os.Open("rdw-1m.csv")
+	source, err := openImportSource(*path, *fromURL)
 	if err != nil {
-		log.Fatal("Error opening file", err)
+		log.Fatal("Error opening CSV source ", err)
 	}
-	defer file.Close()
+	defer source.Close()
 
-	db, err := connectToDB()
+	store, err := openStore()
 	if err != nil {
 		log.Fatal("Error connecting to the database ", err)
 	}
-
-	reader := csv.NewReader(file)
-	// Read and discard the first line (header)
-	if _, err := reader.Read(); err != nil {
-		log.Fatal("Error reading the header line", err)
+	loader, ok := store.(recordLoader)
+	if *loaderName == "load-data" && !ok {
+		log.Fatal("-loader load-data needs DB_DRIVER mysql")
 	}
 
-	const batchSize = 2000
-	var batch []RDWRecord
-	var wg sync.WaitGroup
-	semaphore := make(chan struct{}, 100)
+	// a file is hashed up front, a download while it is read
+	sourceName, size, checksum := rdwResourceURL(voertuigenResource), NullInt{}, ""
+	var input io.Reader = source
+	var downloaded hash.Hash
+	if *fromURL {
+		downloaded = sha256.New()
+		input = io.TeeReader(source, downloaded)
+	} else {
+		sourceName = importFilePath(*path)
+		info, err := os.Stat(sourceName)
+		if err != nil {
+			log.Fatal("Error reading the size of the CSV ", err)
+		}
+		size.Int64, size.Valid = info.Size(), true
+		if checksum, err = fileSHA256(sourceName); err != nil {
+			log.Fatal("Error computing the checksum of the CSV ", err)
+		}
+	}
 
-	for {
-		record, err := reader.Read()
-		if err == io.EOF {
-			break
+	state := importState{table: voertuigenTable, fileHash: checksum, mode: *mode, snapshot: snapshot}
+	if *resume {
+		state, err = store.ImportState(voertuigenTable)
+		if errors.Is(err, sql.ErrNoRows) {
+			log.Fatal("No unfinished import to resume")
 		}
 		if err != nil {
-			log.Fatal("Error reading a record", err)
+			log.Fatal("Error reading the import state ", err)
 		}
-
-		// Convert the record to a typed record
-		rdwRecord := NewRDWRecord(record)
-
-		batch = append(batch, rdwRecord)
-		if len(batch) >= batchSize {
-			wg.Add(1)
-			semaphore <- struct{}{} // Acquire a slot in the semaphore
-			go func(batch []RDWRecord) {
-				defer wg.Done()
-				processRecords(batch, db)
-				<-semaphore // Release a slot in the semaphore
-			}(batch)
-			batch = nil // Start a new batch
+		if state.fileHash != checksum {
+			log.Fatalf("Refusing to resume: %s is not the file of the unfinished import", sourceName)
 		}
+		snapshot = state.snapshot
+		log.Printf("Resuming the %s import of %s from line %d", state.mode, state.snapshot.Format(time.DateOnly), state.line+1)
 	}
 
-	// Process any remaining records that did not make a full batch
-	if len(batch) > 0 {
-		wg.Add(1)
-		semaphore <- struct{}{}
-		go func(batch []RDWRecord) {
-			defer wg.Done()
-			processRecords(batch, db)
-			<-semaphore
-		}(batch)
+	stats := &importStats{}
+	run, err := startImportRun(store, voertuigenTable, sourceName, size, checksum, snapshot, state.mode, stats)
+	if err != nil {
+		log.Fatal("Error recording the import run ", err)
 	}
 
-	wg.Wait() // Wait for all goroutines to finish
-	log.Println("File processed successfully")
-}
+	reader := csv.NewReader(input)
+	header, err := reader.Read()
+	if err != nil {
+		run.fatal("Error reading the header line", err)
+	}
+	// lineOffset and offset turn the positions of a reader that started
+	// halfway the file into positions in the file
+	var lineOffset, offset int64
+	if *resume {
+		if _, err := source.(io.Seeker).Seek(state.offset, io.SeekStart); err != nil {
+			run.fatal("Error seeking to the checkpoint ", err)
+		}
+		reader = csv.NewReader(source)
+		lineOffset, offset = state.line, state.offset
+	} else {
+		state.offset, state.line = reader.InputOffset(), 1
+	}
 
-func processRecords(records []RDWRecord, db *sql.DB) {
-	tx, err := db.Begin()
+	columns, err := newCSVColumnMap(header)
 	if err != nil {
-		log.Fatal("Error starting transaction", err)
+		run.fatal("Error mapping the header line: ", err)
+	}
+	reader.FieldsPerRecord = len(header)
+
+	rejects := newRejectsWriter(*rejectsPath, header)
+	rejects.append = *resume
+	defer rejects.Close()
+
+	table, writeMode := voertuigenTable, state.mode
+	if state.mode == "swap" {
+		if *resume {
+			// the staging table still holds the rows written so far
+			table = stagingTableName(voertuigenTable)
+		} else if table, err = store.CreateStaging(voertuigenTable); err != nil {
+			run.fatal("Error creating staging table ", err)
+		}
+		writeMode = "insert"
+	}
+	// rows after the checkpoint may have been committed already, writing them
+	// again updates them instead of failing on the duplicate
+	if *resume {
+		writeMode = "upsert"
 	}
 
-	stmt, err := tx.Prepare(
-		"INSERT INTO voertuigen (" +
-			"kenteken, voertuigsoort, merk, handelsbenaming, " +
-			"vervaldatum_apk, datum_tenaamstelling, " +
-			"bruto_bpm, " +
-			"inrichting, aantal_zitplaatsen, eerste_kleur, tweede_kleur, " +
-			"aantal_cilinders, cilinderinhoud, " +
-			"massa_ledig_voertuig, toegestane_maximum_massa_voertuig, massa_rijklaar, maximum_massa_trekken_ongeremd, maximum_trekken_massa_geremd, " +
-			"datum_eerste_toelating, datum_eerste_tenaamstelling_in_nederland, " +
-			"wacht_op_keuren, catalogusprijs, wam_verzekerd, " +
-			"maximale_constructiesnelheid, laadvermogen, oplegger_geremd, aanhangwagen_autonoom_geremd, aanhangwagen_middenas_geremd, " +
-			"aantal_staanplaatsen, aantal_deuren, aantal_wielen, " +
-			"afstand_hart_koppeling_tot_achterzijde_voertuig, afstand_voorzijde_voertuig_tot_hart_koppeling, " +
-			"afwijkende_maximum_snelheid, lengte, breedte, " +
-			"europese_voertuigcategorie, europese_voertuigcategorie_toevoeging, europese_uitvoeringcategorie_toevoeging, " +
-			"plaats_chassisnummer, technische_max_massa_voertuig, " +
-			"type, type_gasinstallatie, typegoedkeuringsnummer, variant, uitvoering, " +
-			"volgnummer_wijziging_eu_typegoedkeuring, " +
-			"vermogen_massarijklaar, wielbasis, " +
-			"export_indicator, openstaande_terugroepactie_indicator, " +
-			"vervaldatum_tachograaf, taxi_indicator, " +
-			"maximum_massa_samenstelling, aantal_rolstoelplaatsen, maximum_ondersteunende_snelheid, " +
-			"jaar_laatste_registratie_tellerstand, tellerstandoordeel, code_toelichting_tellerstandoordeel, tenaamstellen_mogelijk, " +
-			"vervaldatum_apk_dt, datum_tenaamstelling_dt, datum_eerste_toelating_dt, datum_eerste_tenaamstelling_in_nederland_dt, vervaldatum_tachograaf_dt, " +
-			"maximum_last_onder_de_vooras_sen_tezamen_koppeling, type_remsysteem_voertuig_code, " +
-			"rupsonderstelconfiguratiecode, wielbasis_voertuig_minimum, wielbasis_voertuig_maximum, " +
-			"lengte_voertuig_minimum, lengte_voertuig_maximum, " +
-			"breedte_voertuig_minimum, breedte_voertuig_maximum, " +
-			"hoogte_voertuig, hoogte_voertuig_minimum, hoogte_voertuig_maximum, " +
-			"massa_bedrijfsklaar_minimaal, massa_bedrijfsklaar_maximaal, " +
-			"technisch_toelaatbaar_massa_koppelpunt, " +
-			"maximum_massa_technisch_maximaal, maximum_massa_technisch_minimaal, " +
-			"subcategorie_nederland, verticale_belasting_koppelpunt_getrokken_voertuig, zuinigheidsclassificatie, " +
-			"registratie_datum_goedkeuring_afschrijvingsmoment_bpm, registratie_datum_goedkeuring_afschrijvingsmoment_bpm_dt, " +
-			"gem_lading_wrde, aerodyn_voorz, massa_alt_aandr, verl_cab_ind, " +
-			"api_gekentekende_voertuigen_assen, api_gekentekende_voertuigen_brandstof, api_gekentekende_voertuigen_carrosserie, api_gekentekende_voertuigen_carrosserie_specifiek, api_gekentekende_voertuigen_voertuigklasse) " +
-			"VALUES (?, ?, ?, ?, " +
-			"?, ?, " +
-			"?, " +
-			"?, ?, ?, ?, " +
-			"?, ?, " +
-			"?, ?, ?, ?, " +
-			"?, ?, " +
-			"?, ?, ?, " +
-			"?, ?, ?, ?, ?, " +
-			"?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);",
-	)
+	stats.read.Store(state.read)
+	stats.rejected.Store(state.rejected)
+	stats.add(writeResult{failed: state.failed, inserted: state.inserted, updated: state.updated, unchanged: state.unchanged})
 
-	if err != nil {
-		log.Fatal("Error preparing statement", err)
+	var checkpoints *checkpointer
+	if checkpointed {
+		if checkpoints, err = newCheckpointer(store, state); err != nil {
+			run.fatal("Error saving the import state ", err)
+		}
+	} else if err := store.DeleteImportState(voertuigenTable); err != nil {
+		run.fatal("Error clearing the import state ", err)
+	}
+
+	var rejectedKentekens []string
+	batches := &csvBatches[RDWRecord]{
+		reader:      reader,
+		convert:     columns.record,
+		rejects:     rejects,
+		rejectsPath: *rejectsPath,
+		maxErrors:   *maxErrors,
+		stats:       stats,
+		lineOffset:  int(lineOffset),
+		rejected: func(values []string) {
+			if plate := columns.kenteken(values); plate != "" {
+				rejectedKentekens = append(rejectedKentekens, plate)
+			}
+		},
+	}
+	writer := newBatchWriter()
+	flush := func(batch []RDWRecord) {
+		position := checkpoints.add(offset+reader.InputOffset(), lineOffset+int64(batches.line), stats.read.Load(), stats.rejected.Load())
+		writer.write(func() {
+			result, err := store.BulkWrite(table, batch, writeMode, snapshot)
+			if err != nil {
+				run.fatal("Error writing batch ", err)
+			}
+			stats.add(result)
+			checkpoints.commit(position, result)
+		})
+	}
+
+	// with -loader load-data all records go into a single LOAD DATA stream
+	// instead of batches
+	var loads chan RDWRecord
+	if *loaderName == "load-data" {
+		loads = make(chan RDWRecord, importBatchSize())
+		writer.write(func() {
+			loaded, err := loader.LoadRecords(table, loads, snapshot)
+			if err != nil {
+				run.fatal("Error loading records ", err)
+			}
+			stats.inserted.Add(loaded)
+			stats.failed.Add(stats.read.Load() - stats.rejected.Load() - loaded)
+		})
+		flush = func(batch []RDWRecord) {
+			for _, record := range batch {
+				loads <- record
+			}
+		}
+	}
+
+	if err := batches.run(flush); err != nil {
+		rejects.Close()
+		run.fatal(err)
+	}
+	if loads != nil {
+		close(loads)
 	}
 
-	defer stmt.Close() // Ensure the statement is closed
-
-	for _, record := range records {
-		_, err = stmt.Exec(
-			record.Kenteken, record.Voertuigsoort, record.Merk, record.Handelsbenaming,
-			record.VervaldatumApk, record.DatumTenaamstelling,
-			record.BrutoBpm,
-			record.Inrichting, record.AantalZitplaatsen, record.EersteKleur, record.TweedeKleur,
-			record.AantalCilinders, record.Cilinderinhoud,
-			record.MassaLedigVoertuig, record.ToegestaneMaximumMassaVoertuig, record.MassaRijklaar, record.MaximumTrekkenMassaOngeremd, record.MaximumTrekkenMassaGeremd,
-			record.DatumEersteToelating, record.DatumEersteTenaamstallingNL,
-			record.WachtOpKeuren, record.Catalogusprijs, record.WamVerzekerd,
-			record.MaxSnelheid, record.Laadvermogen, record.OpleggerGeremd, record.AanhangwagenAutonoomGeremd, record.AanhangwagenMiddenasGeremd,
-			record.AantalStaanplaatsen, record.AantalDeuren, record.AantalWielen,
-			record.AfstandHartKoppelingTotAchterzijdeVoertuig, record.AfstandVoorzijdeVoertuigTotHartKoppeling,
-			record.AfwijkendeMaximumSnelheid, record.Lengte, record.Breedte,
-			record.EuropeseVoertuigCategorie, record.EuropeseVoertuigCategorieToevoeging, record.EuropeseUitvoeringcategorieToevoeging,
-			record.PlaatsChassisnummer, record.TechnischeMaxMassaVoertuig,
-			record.Type, record.TypeGasinstallatie, record.Typegoedkeuringsnummer, record.Variant, record.Uitvoering,
-			record.VolgnummerWijzigingEuTypegoedkeuring,
-			record.VermoegenMassarijklaar, record.Wielbasis,
-			record.exportIndicator, record.OpenstaandeTerugroepactieIndicator,
-			record.VervaldatumTachograaf, record.TaxiIndicator,
-			record.MaximumMassaSamenstelling, record.AantalRolstoelplaatsen, record.MaximumOndersteunendeSnelheid,
-			record.JaarLaatsteRegistratieTellerstand, record.Tellerstandoordeel, record.CodeToelichtingTellerstandoordeel, record.TenaamstellenMogelijk,
-			record.VervaldatumApkDt, record.DatumTenaamstellingDt, record.DatumEersteToelatingDt, record.DatumEersteTenaamstellingInNederlandDt, record.VervaldatumTachograafDt,
-			record.MaximumLastOnderDeVoorasSenTezamenKoppeling, record.TypeRemsysteemVoertuigCode,
-			record.Rupsonderstelconfiguratiecode, record.WielbasisVoertuigMinimum, record.WielbasisVoertuigMaximum,
-			record.LengteVoertuigMinimum, record.LengteVoertuigMaximum,
-			record.BreedteVoertuigMinimum, record.BreedteVoertuigMaximum,
-			record.HoogteVoertuig, record.HoogteVoertuigMinimum, record.HoogteVoertuigMaximum,
-			record.MassaBedrijfsklaarMinimaal, record.MassaBedrijfsklaarMaximaal,
-			record.TechnischToelaatbaarMassaKoppelpunt,
-			record.MaximumMassaTechnischMaximaal, record.MaximumMassaTechnischMinimaal,
-			record.SubcategorieNederland, record.VerticaleBelastingKoppelpuntGetrokkenVoertuig, record.Zuinigheidsclassificatie,
-			record.RegistratieDatumGoedkeuringAfschrijvingsmomentBpm, record.RegistratieDatumGoedkeuringAfschrijvingsmomentBpmDt,
-			record.GemLadingWrde, record.AerodynVoorz, record.MassaAltAandr, record.VerlCabInd,
-			record.ApiGekentekendeVoertuigenAssen, record.ApiGekentekendeVoertuigenBrandstof, record.ApiGekentekendeVoertuigenCarrosserie, record.ApiGekentekendeVoertuigenCarrosserieSpecifiek, record.ApiGekentekendeVoertuigenVoertuigklasse,
-		)
+	writer.wait()
+	log.Printf("Read %d records, %d rejected, %d failed to insert", stats.read.Load(), stats.rejected.Load(), stats.failed.Load())
+	log.Printf("Inserted %d, updated %d, unchanged %d", stats.inserted.Load(), stats.updated.Load(), stats.unchanged.Load())
+
+	if state.mode == "swap" {
+		written := stats.read.Load() - stats.rejected.Load() - stats.failed.Load()
+		if err := store.ValidateStaging(voertuigenTable, table, written, []string{"kenteken", "merk"}); err != nil {
+			run.fatal("Staging table failed validation, keeping the current table: ", err)
+		}
+		backup, err := store.SwapStaging(voertuigenTable, table)
 		if err != nil {
-			log.Println("Error inserting record ", err)
+			run.fatal("Error swapping staging table into place ", err)
 		}
+		log.Printf("Swapped %s into place, previous table kept as %s", table, backup)
 	}
 
-	err = tx.Commit()
-	if err != nil {
-		tx.Rollback()
-		log.Fatal("Error committing transaction ", err)
+	if *mode == "delta" {
+		if stats.failed.Load() > 0 {
+			run.fatal("Not marking removed vehicles because some records failed to write")
+		}
+		// a rejected row still means the vehicle is in the snapshot
+		removed, err := store.MarkRemoved(snapshot, rejectedKentekens)
+		if err != nil {
+			run.fatal("Error marking removed vehicles ", err)
+		}
+		log.Printf("Marked %d vehicles as removed", removed)
+	}
+
+	if downloaded != nil {
+		run.hash = hex.EncodeToString(downloaded.Sum(nil))
+		run.size.Int64, run.size.Valid = reader.InputOffset(), true
+	}
+	run.finish("succeeded", "")
+	checkpoints.finish()
+	log.Println("File processed successfully")
+}
+
+// openImportSource opens the local CSV file or starts the download from the RDW.
+func openImportSource(path string, fromURL bool) (io.ReadCloser, error) {
+	if fromURL {
+		url := rdwResourceURL(voertuigenResource)
+		log.Printf("Downloading %s", url)
+		return openRDWDownload(url)
 	}
 
+	return os.Open(importFilePath(path))
 }
 
-func NewRDWRecord(record []string) RDWRecord {
-	return RDWRecord{
-		Kenteken:                       record[0],
-		Voertuigsoort:                  record[1],
-		Merk:                           record[2],
-		Handelsbenaming:                record[3],
-		VervaldatumApk:                 parseDateRdwFormat(record[4]),
-		DatumTenaamstelling:            parseDateRdwFormat(record[5]),
-		BrutoBpm:                       stringToDecimal(record[6]),
-		Inrichting:                     record[7],
-		AantalZitplaatsen:              stringToInt(record[8]),
-		EersteKleur:                    record[9],
-		TweedeKleur:                    record[10],
-		AantalCilinders:                stringToInt(record[11]),
-		Cilinderinhoud:                 stringToInt(record[12]),
-		MassaLedigVoertuig:             stringToInt(record[13]),
-		ToegestaneMaximumMassaVoertuig: stringToInt(record[14]),
-		MassaRijklaar:                  stringToInt(record[15]),
-		MaximumTrekkenMassaOngeremd:    stringToInt(record[16]),
-		MaximumTrekkenMassaGeremd:      stringToInt(record[17]),
-		DatumEersteToelating:           parseDateRdwFormat(record[18]),
-		DatumEersteTenaamstallingNL:    parseDateRdwFormat(record[19]),
-		WachtOpKeuren:                  record[20],
-		Catalogusprijs:                 stringToDecimal(record[21]),
-		WamVerzekerd:                   record[22],
-		MaxSnelheid:                    stringToInt(record[23]),
-		Laadvermogen:                   stringToInt(record[24]),
-		OpleggerGeremd:                 stringToInt(record[25]),
-		AanhangwagenAutonoomGeremd:     stringToInt(record[26]),
-		AanhangwagenMiddenasGeremd:     stringToInt(record[27]),
-		AantalStaanplaatsen:            stringToInt(record[28]),
-		AantalDeuren:                   stringToInt(record[29]),
-		AantalWielen:                   stringToInt(record[30]),
-		AfstandHartKoppelingTotAchterzijdeVoertuig:          stringToInt(record[31]),
-		AfstandVoorzijdeVoertuigTotHartKoppeling:            stringToInt(record[32]),
-		AfwijkendeMaximumSnelheid:                           stringToInt(record[33]),
-		Lengte:                                              stringToInt(record[34]),
-		Breedte:                                             stringToInt(record[35]),
-		EuropeseVoertuigCategorie:                           record[36],
-		EuropeseVoertuigCategorieToevoeging:                 record[37],
-		EuropeseUitvoeringcategorieToevoeging:               record[38],
-		PlaatsChassisnummer:                                 record[39],
-		TechnischeMaxMassaVoertuig:                          stringToInt(record[40]),
-		Type:                                                record[41],
-		TypeGasinstallatie:                                  record[42],
-		Typegoedkeuringsnummer:                              record[43],
-		Variant:                                             record[44],
-		Uitvoering:                                          record[45],
-		VolgnummerWijzigingEuTypegoedkeuring:                stringToInt(record[46]),
-		VermoegenMassarijklaar:                              stringToDecimal(record[47]),
-		Wielbasis:                                           stringToInt(record[48]),
-		exportIndicator:                                     record[49],
-		OpenstaandeTerugroepactieIndicator:                  record[50],
-		VervaldatumTachograaf:                               parseDateRdwFormat(record[51]),
-		TaxiIndicator:                                       record[52],
-		MaximumMassaSamenstelling:                           stringToInt(record[53]),
-		AantalRolstoelplaatsen:                              stringToInt(record[54]),
-		MaximumOndersteunendeSnelheid:                       stringToDecimal(record[55]),
-		JaarLaatsteRegistratieTellerstand:                   stringToInt(record[56]),
-		Tellerstandoordeel:                                  record[57],
-		CodeToelichtingTellerstandoordeel:                   record[58],
-		TenaamstellenMogelijk:                               record[59],
-		VervaldatumApkDt:                                    parseISO8601Date(record[60]),
-		DatumTenaamstellingDt:                               parseISO8601Date(record[61]),
-		DatumEersteToelatingDt:                              parseISO8601Date(record[62]),
-		DatumEersteTenaamstellingInNederlandDt:              parseISO8601Date(record[63]),
-		VervaldatumTachograafDt:                             parseISO8601Date(record[64]),
-		MaximumLastOnderDeVoorasSenTezamenKoppeling:         stringToInt(record[65]),
-		TypeRemsysteemVoertuigCode:                          record[66],
-		Rupsonderstelconfiguratiecode:                       record[67],
-		WielbasisVoertuigMinimum:                            stringToInt(record[68]),
-		WielbasisVoertuigMaximum:                            stringToInt(record[69]),
-		LengteVoertuigMinimum:                               stringToInt(record[70]),
-		LengteVoertuigMaximum:                               stringToInt(record[71]),
-		BreedteVoertuigMinimum:                              stringToInt(record[72]),
-		BreedteVoertuigMaximum:                              stringToInt(record[73]),
-		HoogteVoertuig:                                      stringToDecimal(record[74]),
-		HoogteVoertuigMinimum:                               stringToDecimal(record[75]),
-		HoogteVoertuigMaximum:                               stringToDecimal(record[76]),
-		MassaBedrijfsklaarMinimaal:                          stringToInt(record[77]),
-		MassaBedrijfsklaarMaximaal:                          stringToInt(record[78]),
-		TechnischToelaatbaarMassaKoppelpunt:                 stringToInt(record[79]),
-		MaximumMassaTechnischMaximaal:                       stringToInt(record[80]),
-		MaximumMassaTechnischMinimaal:                       stringToInt(record[81]),
-		SubcategorieNederland:                               record[82],
-		VerticaleBelastingKoppelpuntGetrokkenVoertuig:       stringToInt(record[83]),
-		Zuinigheidsclassificatie:                            record[84],
-		RegistratieDatumGoedkeuringAfschrijvingsmomentBpm:   parseDateRdwFormat(record[85]),
-		RegistratieDatumGoedkeuringAfschrijvingsmomentBpmDt: parseISO8601Date(record[86]),
-		GemLadingWrde:                                       stringToDecimal(record[87]),
-		AerodynVoorz:                                        record[88],
-		MassaAltAandr:                                       stringToInt(record[89]),
-		VerlCabInd:                                          record[90],
-		ApiGekentekendeVoertuigenAssen:                      record[91],
-		ApiGekentekendeVoertuigenBrandstof:                  record[92],
-		ApiGekentekendeVoertuigenCarrosserie:                record[93],
-		ApiGekentekendeVoertuigenCarrosserieSpecifiek:       record[94],
-		ApiGekentekendeVoertuigenVoertuigklasse:             record[95],
+// importFilePath returns the CSV file to import, path or rdw-1m.csv.
+func importFilePath(path string) string {
+	if path == "" {
+		return defaultCSVFile
 	}
+	return path
 }
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	defaultRDWBaseURL       = "https://opendata.rdw.nl"
+	defaultDownloadAttempts = 5
+
+	// voertuigenResource is the Gekentekende_voertuigen dataset
+	voertuigenResource = "m9d7-ebf2"
+)
+
+// rdwResourceURL returns the full CSV export URL of an RDW open data resource.
+// RDW_BASE_URL can point it at a mirror or a local stand-in server.
+func rdwResourceURL(resource string) string {
+	baseURL := getEnvVar("RDW_BASE_URL")
+	if baseURL == "" {
+		baseURL = defaultRDWBaseURL
+	}
+	return strings.TrimSuffix(baseURL, "/") + "/resource/" + resource + ".csv?$limit=99999999999999999999"
+}
+
+// rdwDownload streams a CSV from the RDW open data portal without storing it
+// on disk. When the connection drops halfway it reconnects with a Range
+// request and continues where it left off.
+type rdwDownload struct {
+	url         string
+	client      *http.Client
+	body        io.ReadCloser
+	offset      int64
+	maxAttempts int
+}
+
+// openRDWDownload starts downloading url, retrying transient failures up to
+// DOWNLOAD_ATTEMPTS times (default 5).
+func openRDWDownload(url string) (*rdwDownload, error) {
+	maxAttempts := getIntEnvVar("DOWNLOAD_ATTEMPTS")
+	if maxAttempts <= 0 {
+		maxAttempts = defaultDownloadAttempts
+	}
+
+	d := &rdwDownload{url: url, client: &http.Client{}, maxAttempts: maxAttempts}
+	if err := d.connect(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (d *rdwDownload) Read(p []byte) (int, error) {
+	for attempt := 1; ; attempt++ {
+		if d.body == nil {
+			if err := d.connect(); err != nil {
+				return 0, err
+			}
+		}
+
+		n, err := d.body.Read(p)
+		d.offset += int64(n)
+		if err == nil || err == io.EOF {
+			return n, err
+		}
+
+		d.body.Close()
+		d.body = nil
+		if n > 0 {
+			// hand out what we have, the next Read resumes the download
+			return n, nil
+		}
+		if attempt >= d.maxAttempts {
+			return 0, fmt.Errorf("download interrupted at byte %d: %w", d.offset, err)
+		}
+		log.Printf("Download interrupted at byte %d, resuming: %v", d.offset, err)
+	}
+}
+
+func (d *rdwDownload) Close() error {
+	if d.body == nil {
+		return nil
+	}
+	return d.body.Close()
+}
+
+// connect (re)opens the response body at the current offset, with an
+// exponential backoff between failed attempts.
+func (d *rdwDownload) connect() error {
+	var err error
+	for attempt := 1; attempt <= d.maxAttempts; attempt++ {
+		if attempt > 1 {
+			backoff := time.Duration(1<<(attempt-2)) * time.Second
+			log.Printf("Retrying download in %s: %v", backoff, err)
+			time.Sleep(backoff)
+		}
+
+		var retry bool
+		retry, err = d.request()
+		if err == nil || !retry {
+			return err
+		}
+	}
+	return fmt.Errorf("giving up after %d attempts: %w", d.maxAttempts, err)
+}
+
+// request performs a single GET and reports whether a failure is worth
+// retrying.
+func (d *rdwDownload) request() (bool, error) {
+	req, err := http.NewRequest(http.MethodGet, d.url, nil)
+	if err != nil {
+		return false, err
+	}
+	if d.offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", d.offset))
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusPartialContent && d.offset > 0:
+		d.body = resp.Body
+	case resp.StatusCode == http.StatusOK:
+		// the server ignored the Range header, skip what we already read
+		if _, err := io.CopyN(io.Discard, resp.Body, d.offset); err != nil {
+			resp.Body.Close()
+			return true, err
+		}
+		d.body = resp.Body
+	default:
+		resp.Body.Close()
+		err := fmt.Errorf("unexpected HTTP status %s from %s", resp.Status, d.url)
+		return isTransientStatus(resp.StatusCode), err
+	}
+	return false, nil
+}
+
+func isTransientStatus(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
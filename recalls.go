@@ -0,0 +1,128 @@
+package main
+
+import (
+	"database/sql"
+)
+
+// recallStatusOpen is the code_status of a recall that still has to be
+// carried out on the vehicle.
+const recallStatusOpen = "O"
+
+// recallDatasets are the RDW terugroepactie datasets: the status of every
+// recall per kenteken, and per recall its description, risks and remedies.
+// Their tables are defined in the migrations.
+var recallDatasets = []rdwDataset{
+	{
+		name:     "terugroepactie_status",
+		resource: "t49b-isb7",
+		table:    "terugroepacties_status",
+		key:      []string{"kenteken", "referentiecode_rdw"},
+		columns: []datasetColumn{
+			{"kenteken", textColumn},
+			{"referentiecode_rdw", textColumn},
+			{"code_status", textColumn},
+			{"status", textColumn},
+		},
+	},
+	{
+		name:     "terugroepactie",
+		resource: "j9yg-7rg9",
+		table:    "terugroepacties",
+		key:      []string{"referentiecode_rdw"},
+		columns: []datasetColumn{
+			{"referentiecode_rdw", textColumn},
+			{"referentiecode_fabrikant", textColumn},
+			{"publicatiedatum_rdw", dateColumn},
+			{"omschrijving_defect", longTextColumn},
+			{"omschrijving_gevolg", longTextColumn},
+		},
+	},
+	{
+		name:     "terugroepactie_risico",
+		resource: "9ihi-jgpf",
+		table:    "terugroepacties_risico",
+		key:      []string{"referentiecode_rdw"},
+		columns: []datasetColumn{
+			{"referentiecode_rdw", textColumn},
+			{"risico_omschrijving", longTextColumn},
+		},
+	},
+	{
+		name:     "terugroepactie_herstel",
+		resource: "mu6k-3b2k",
+		table:    "terugroepacties_herstel",
+		key:      []string{"referentiecode_rdw"},
+		columns: []datasetColumn{
+			{"referentiecode_rdw", textColumn},
+			{"herstel_omschrijving", longTextColumn},
+		},
+	},
+}
+
+// recall is one terugroepactie of a vehicle with everything known about it.
+type recall struct {
+	ReferentiecodeRdw string           `json:"referentiecode_rdw"`
+	CodeStatus        string           `json:"code_status"`
+	Status            string           `json:"status"`
+	Details           map[string]any   `json:"details"`
+	Risicos           []map[string]any `json:"risicos"`
+	Herstel           []map[string]any `json:"herstel"`
+}
+
+// voertuigRecalls splits the recalls of a vehicle into open and closed ones.
+type voertuigRecalls struct {
+	Kenteken string   `json:"kenteken"`
+	Open     []recall `json:"open"`
+	Closed   []recall `json:"closed"`
+}
+
+// fetches every recall of a vehicle with its description, risks and remedies
+func getVoertuigRecalls(db *sql.DB, kenteken string) (voertuigRecalls, error) {
+	result := voertuigRecalls{Kenteken: kenteken, Open: []recall{}, Closed: []recall{}}
+
+	rows, err := db.Query("SELECT referentiecode_rdw, code_status, status FROM terugroepacties_status WHERE kenteken = ? ORDER BY referentiecode_rdw", kenteken)
+	if err != nil {
+		return result, err
+	}
+	var recalls []recall
+	for rows.Next() {
+		var r recall
+		var codeStatus, status sql.NullString
+		if err := rows.Scan(&r.ReferentiecodeRdw, &codeStatus, &status); err != nil {
+			rows.Close()
+			return result, err
+		}
+		r.CodeStatus, r.Status = codeStatus.String, status.String
+		recalls = append(recalls, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return result, err
+	}
+
+	details, _ := findDataset(recallDatasets, "terugroepactie")
+	risicos, _ := findDataset(recallDatasets, "terugroepactie_risico")
+	herstel, _ := findDataset(recallDatasets, "terugroepactie_herstel")
+	for _, r := range recalls {
+		rows, err := getDatasetRows(db, details, "referentiecode_rdw", r.ReferentiecodeRdw)
+		if err != nil {
+			return result, err
+		}
+		if len(rows) > 0 {
+			r.Details = rows[0]
+		}
+		if r.Risicos, err = getDatasetRows(db, risicos, "referentiecode_rdw", r.ReferentiecodeRdw); err != nil {
+			return result, err
+		}
+		if r.Herstel, err = getDatasetRows(db, herstel, "referentiecode_rdw", r.ReferentiecodeRdw); err != nil {
+			return result, err
+		}
+
+		if r.CodeStatus == recallStatusOpen {
+			result.Open = append(result.Open, r)
+		} else {
+			result.Closed = append(result.Closed, r)
+		}
+	}
+	return result, nil
+}
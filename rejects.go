@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const (
+	defaultRejectsFile = "rejects.csv"
+	defaultMaxErrors   = 1000
+
+	// rejectErrorColumn is appended to the rejects file. The importer ignores
+	// it, so a corrected rejects file can be imported as is.
+	rejectErrorColumn = "import_error"
+)
+
+// rowError describes a CSV value that could not be converted.
+type rowError struct {
+	Line   int
+	Column string
+	Value  string
+	Err    error
+}
+
+func (e *rowError) Error() string {
+	return fmt.Sprintf("line %d, column %s, value %q: %v", e.Line, e.Column, e.Value, e.Err)
+}
+
+func (e *rowError) Unwrap() error {
+	return e.Err
+}
+
+// rejectsWriter writes rows that failed to convert to a CSV with the same
+// header as the source plus an import_error column. The file is only created
+// once the first row is rejected. With append set, rows are added to an
+// existing file, as a resumed import does.
+type rejectsWriter struct {
+	path   string
+	header []string
+	append bool
+	file   *os.File
+	writer *csv.Writer
+}
+
+func newRejectsWriter(path string, header []string) *rejectsWriter {
+	return &rejectsWriter{path: path, header: header}
+}
+
+func (w *rejectsWriter) write(row []string, err error) error {
+	if w.writer == nil {
+		flag := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+		if w.append {
+			flag = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+		}
+		file, err := os.OpenFile(w.path, flag, 0666)
+		if err != nil {
+			return err
+		}
+		w.file = file
+		w.writer = csv.NewWriter(file)
+		info, err := file.Stat()
+		if err != nil {
+			return err
+		}
+		// a file appended to already has its header
+		if info.Size() == 0 {
+			if err := w.writer.Write(append(w.header[:len(w.header):len(w.header)], rejectErrorColumn)); err != nil {
+				return err
+			}
+		}
+	}
+	message := strings.ReplaceAll(err.Error(), "\n", "; ")
+	if err := w.writer.Write(append(row[:len(row):len(row)], message)); err != nil {
+		return err
+	}
+	// flushed right away so the rows before a checkpoint are on disk when
+	// the import dies
+	w.writer.Flush()
+	return w.writer.Error()
+}
+
+func (w *rejectsWriter) Close() error {
+	if w.writer == nil {
+		return nil
+	}
+	w.writer.Flush()
+	if err := w.writer.Error(); err != nil {
+		w.file.Close()
+		return err
+	}
+	return w.file.Close()
+}
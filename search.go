@@ -0,0 +1,238 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultSearchLimit    = 100
+	defaultSearchMaxLimit = 1000
+)
+
+// searchKind decides which operators a filterable column supports and how
+// its values are parsed.
+type searchKind int
+
+const (
+	searchText searchKind = iota
+	searchDate
+	searchNumber
+)
+
+// searchColumns is the whitelist of columns the search endpoint filters on.
+// Every column has an index, see the search_indexes migration. Date and number columns
+// can also be used to sort on.
+var searchColumns = map[string]searchKind{
+	"merk":                   searchText,
+	"handelsbenaming":        searchText,
+	"voertuigsoort":          searchText,
+	"eerste_kleur":           searchText,
+	"datum_eerste_toelating": searchDate,
+	"vervaldatum_apk":        searchDate,
+	"catalogusprijs":         searchNumber,
+	"massa_rijklaar":         searchNumber,
+	"cilinderinhoud":         searchNumber,
+}
+
+// searchOperators maps the operator suffix of a query parameter to SQL.
+var searchOperators = map[string]string{
+	"":    "=",
+	"gt":  ">",
+	"gte": ">=",
+	"lt":  "<",
+	"lte": "<=",
+}
+
+// searchQuery is a parsed search request: merk=TESLA&catalogusprijs.lte=50000
+// &sort=-datum_eerste_toelating&limit=50&cursor=...
+type searchQuery struct {
+	conditions []string
+	args       []any
+	sort       string
+	descending bool
+	limit      int
+	cursor     *searchCursor
+	fields     projection
+}
+
+// searchCursor is the position after the last row of a page: the value of
+// the sort column and the kenteken, which breaks ties.
+type searchCursor struct {
+	Value    string `json:"v,omitempty"`
+	Kenteken string `json:"k"`
+}
+
+// parseSearchQuery validates the query parameters against the whitelist.
+// The limit is capped at maxLimit.
+func parseSearchQuery(values url.Values, maxLimit int) (*searchQuery, error) {
+	q := &searchQuery{sort: "kenteken", limit: defaultSearchLimit, fields: voertuigColumns}
+
+	for param, list := range values {
+		switch param {
+		case "sort":
+			q.sort = strings.TrimPrefix(list[0], "-")
+			q.descending = strings.HasPrefix(list[0], "-")
+			if kind, ok := searchColumns[q.sort]; q.sort != "kenteken" && (!ok || kind == searchText) {
+				return nil, fmt.Errorf("cannot sort on %q", q.sort)
+			}
+			continue
+		case "limit":
+			limit, err := strconv.Atoi(list[0])
+			if err != nil || limit < 1 {
+				return nil, fmt.Errorf("invalid limit %q", list[0])
+			}
+			q.limit = min(limit, maxLimit)
+			continue
+		case "fields":
+			fields, err := parseProjection(list[0])
+			if err != nil {
+				return nil, err
+			}
+			q.fields = fields
+			continue
+		case "cursor":
+			cursor, err := decodeSearchCursor(list[0])
+			if err != nil {
+				return nil, fmt.Errorf("invalid cursor")
+			}
+			q.cursor = cursor
+			continue
+		}
+
+		column, operator, _ := strings.Cut(param, ".")
+		kind, ok := searchColumns[column]
+		if !ok {
+			return nil, fmt.Errorf("cannot filter on %q", column)
+		}
+		sqlOperator, ok := searchOperators[operator]
+		if !ok || kind == searchText && operator != "" {
+			return nil, fmt.Errorf("unsupported operator %q for %s", operator, column)
+		}
+		for _, raw := range list {
+			value, err := parseSearchValue(kind, raw)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value %q for %s: %w", raw, column, err)
+			}
+			q.conditions = append(q.conditions, column+" "+sqlOperator+" ?")
+			q.args = append(q.args, value)
+		}
+	}
+
+	if q.cursor != nil && q.sort != "kenteken" {
+		if _, err := parseSearchValue(searchColumns[q.sort], q.cursor.Value); err != nil {
+			return nil, fmt.Errorf("invalid cursor")
+		}
+	}
+	return q, nil
+}
+
+func parseSearchValue(kind searchKind, raw string) (any, error) {
+	switch kind {
+	case searchDate:
+		return time.Parse(time.DateOnly, raw)
+	case searchNumber:
+		return strconv.ParseFloat(raw, 64)
+	default:
+		return raw, nil
+	}
+}
+
+// sql returns the query for one page, fetching one row more than the limit to
+// know whether there is a next page. Rows where the sort column is NULL are
+// left out, they have no place in the keyset order. Besides the requested
+// fields it selects the kenteken and sort column the cursor is made of.
+func (q *searchQuery) sql() (string, []any) {
+	conditions := append([]string{}, q.conditions...)
+	args := append([]any{}, q.args...)
+
+	comparison, order := ">", "ASC"
+	if q.descending {
+		comparison, order = "<", "DESC"
+	}
+
+	if q.sort != "kenteken" {
+		conditions = append(conditions, q.sort+" IS NOT NULL")
+	}
+	if q.cursor != nil {
+		if q.sort == "kenteken" {
+			conditions = append(conditions, "kenteken "+comparison+" ?")
+			args = append(args, q.cursor.Kenteken)
+		} else {
+			value, _ := parseSearchValue(searchColumns[q.sort], q.cursor.Value)
+			conditions = append(conditions, "("+q.sort+" "+comparison+" ? OR ("+q.sort+" = ? AND kenteken "+comparison+" ?))")
+			args = append(args, value, value, q.cursor.Kenteken)
+		}
+	}
+
+	query := "SELECT " + q.fields.with("kenteken", q.sort).columnList() + " FROM voertuigen"
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY "
+	if q.sort != "kenteken" {
+		query += q.sort + " " + order + ", "
+	}
+	query += "kenteken " + order + " LIMIT " + strconv.Itoa(q.limit+1)
+	return query, args
+}
+
+// nextCursor returns the cursor pointing after record.
+func (q *searchQuery) nextCursor(record *RDWRecord) string {
+	cursor := searchCursor{Kenteken: record.Kenteken}
+	if q.sort != "kenteken" {
+		for _, column := range voertuigColumns {
+			if column.name == q.sort {
+				cursor.Value, _ = fieldString(column.field(record))
+			}
+		}
+	}
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeSearchCursor(s string) (*searchCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	var cursor searchCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, err
+	}
+	return &cursor, nil
+}
+
+// fetches one page of search results and the cursor of the next page, which is empty on the last page
+func searchVoertuigen(db *sql.DB, q *searchQuery) ([]RDWRecord, string, error) {
+	query, args := q.sql()
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	records := []RDWRecord{}
+	for rows.Next() {
+		var record RDWRecord
+		if err := rows.Scan(q.fields.with("kenteken", q.sort).scanDest(&record)...); err != nil {
+			return nil, "", err
+		}
+		records = append(records, record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+
+	if len(records) <= q.limit {
+		return records, "", nil
+	}
+	records = records[:q.limit]
+	return records, q.nextCursor(&records[q.limit-1]), nil
+}
@@ -0,0 +1,595 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	defaultSodaLimit    = 1000
+	defaultSodaMaxLimit = 50000
+)
+
+// sodaQuery is a SoQL query ($select, $where, $order, $limit, $offset and
+// simple column=value filters) on voertuigen, as understood by the RDW open
+// data API. Only a subset of SoQL is supported: no aggregates, functions
+// other than starts_with, or full text search.
+type sodaQuery struct {
+	selects []sodaSelect
+	where   []string
+	args    []any
+	order   []string
+	limit   int
+	offset  int
+}
+
+// sodaSelect is a selected column and the name it gets in the output.
+type sodaSelect struct {
+	column voertuigColumn
+	alias  string
+}
+
+// parseSodaQuery parses the query parameters into parameterized SQL parts.
+// Column names are checked against voertuigColumns and every literal becomes
+// a statement argument converted to the type of the column it is compared to.
+func parseSodaQuery(values url.Values, maxLimit int) (*sodaQuery, error) {
+	q := &sodaQuery{limit: defaultSodaLimit}
+
+	for param, list := range values {
+		value := list[0]
+		var err error
+		switch param {
+		case "$select":
+			q.selects, err = parseSodaSelect(value)
+		case "$where":
+			p, perr := newSodaParser(value)
+			if perr != nil {
+				return nil, perr
+			}
+			var condition string
+			if condition, err = p.parseWhere(); err == nil {
+				q.where = append(q.where, "("+condition+")")
+				q.args = append(q.args, p.args...)
+			}
+		case "$order":
+			q.order, err = parseSodaOrder(value)
+		case "$limit":
+			q.limit, err = strconv.Atoi(value)
+			if err != nil || q.limit < 0 {
+				return nil, fmt.Errorf("invalid $limit %q", value)
+			}
+			q.limit = min(q.limit, maxLimit)
+		case "$offset":
+			q.offset, err = strconv.Atoi(value)
+			if err != nil || q.offset < 0 {
+				return nil, fmt.Errorf("invalid $offset %q", value)
+			}
+		default:
+			if strings.HasPrefix(param, "$") {
+				return nil, fmt.Errorf("unsupported parameter %s", param)
+			}
+			column, ok := findVoertuigColumn(param)
+			if !ok {
+				return nil, fmt.Errorf("no such column: %s", param)
+			}
+			var arg any
+			if arg, err = sodaLiteral(column, value); err == nil {
+				q.where = append(q.where, column.name+" = ?")
+				q.args = append(q.args, arg)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if q.selects == nil {
+		for _, column := range voertuigColumns {
+			q.selects = append(q.selects, sodaSelect{column: column, alias: column.name})
+		}
+	}
+	return q, nil
+}
+
+// sql returns the query for q. Rows are always ordered by kenteken last, so
+// paging with $offset is stable.
+func (q *sodaQuery) sql() (string, []any) {
+	names := make([]string, len(q.selects))
+	for i, s := range q.selects {
+		names[i] = s.column.name
+	}
+
+	query := "SELECT " + strings.Join(names, ", ") + " FROM voertuigen"
+	if len(q.where) > 0 {
+		query += " WHERE " + strings.Join(q.where, " AND ")
+	}
+	query += " ORDER BY " + strings.Join(append(q.order, "kenteken"), ", ")
+	query += " LIMIT " + strconv.Itoa(q.limit) + " OFFSET " + strconv.Itoa(q.offset)
+	return query, q.args
+}
+
+// fetches the rows of a SoQL query in the SODA output format: every value as a string and NULL values left out
+func querySoda(db *sql.DB, q *sodaQuery) ([]map[string]string, error) {
+	query, args := q.sql()
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []map[string]string{}
+	for rows.Next() {
+		var record RDWRecord
+		dest := make([]any, len(q.selects))
+		for i, s := range q.selects {
+			dest[i] = s.column.field(&record)
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+
+		object := make(map[string]string, len(dest))
+		for i, s := range q.selects {
+			if value, ok := sodaValue(s.column, dest[i]); ok {
+				object[s.alias] = value
+			}
+		}
+		result = append(result, object)
+	}
+	return result, rows.Err()
+}
+
+// sodaValue formats a field the way the RDW API does: dates as yyyymmdd,
+// except for the _dt columns which are floating timestamps, and numbers
+// without trailing zeros. Empty text counts as NULL.
+func sodaValue(column voertuigColumn, field any) (string, bool) {
+	switch field := field.(type) {
+	case *string:
+		return *field, *field != ""
+	case *NullDecimal:
+		return strconv.FormatFloat(field.Float64, 'f', -1, 64), field.Valid
+	case *NullDate:
+		if strings.HasSuffix(column.name, "_dt") {
+			return field.Time.Format("2006-01-02T15:04:05.000"), field.Valid
+		}
+		return field.Time.Format("20060102"), field.Valid
+	default:
+		return fieldString(field)
+	}
+}
+
+// sodaDateLayouts are the date literals accepted in queries, for both the
+// yyyymmdd text columns and the _dt timestamp columns.
+var sodaDateLayouts = []string{"20060102", time.DateOnly, "2006-01-02T15:04:05.000", "2006-01-02T15:04:05"}
+
+// sodaLiteral converts a literal to a statement argument for column.
+func sodaLiteral(column voertuigColumn, raw string) (any, error) {
+	switch column.field(&RDWRecord{}).(type) {
+	case *NullInt, *NullDecimal:
+		value, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s is a number, got %q", column.name, raw)
+		}
+		return value, nil
+	case *NullDate:
+		for _, layout := range sodaDateLayouts {
+			if value, err := time.Parse(layout, raw); err == nil {
+				return value, nil
+			}
+		}
+		return nil, fmt.Errorf("%s is a date, got %q", column.name, raw)
+	default:
+		return raw, nil
+	}
+}
+
+// findVoertuigColumn returns the column of voertuigen with the given name.
+func findVoertuigColumn(name string) (voertuigColumn, bool) {
+	for _, column := range voertuigColumns {
+		if column.name == name {
+			return column, true
+		}
+	}
+	return voertuigColumn{}, false
+}
+
+func parseSodaSelect(s string) ([]sodaSelect, error) {
+	p, err := newSodaParser(s)
+	if err != nil {
+		return nil, err
+	}
+	if p.accept("*") && p.done() {
+		return nil, nil
+	}
+	p.pos = 0
+
+	var selects []sodaSelect
+	for {
+		column, err := p.parseColumn()
+		if err != nil {
+			return nil, err
+		}
+		alias := column.name
+		if p.acceptKeyword("AS") {
+			token := p.next()
+			if token.kind != sodaIdent {
+				return nil, fmt.Errorf("expected alias after AS, got %q", token.text)
+			}
+			alias = token.text
+		}
+		selects = append(selects, sodaSelect{column: column, alias: alias})
+		if !p.accept(",") {
+			break
+		}
+	}
+	if !p.done() {
+		return nil, fmt.Errorf("unexpected %q in $select", p.peek().text)
+	}
+	return selects, nil
+}
+
+func parseSodaOrder(s string) ([]string, error) {
+	p, err := newSodaParser(s)
+	if err != nil {
+		return nil, err
+	}
+
+	var order []string
+	for {
+		column, err := p.parseColumn()
+		if err != nil {
+			return nil, err
+		}
+		direction := "ASC"
+		if p.acceptKeyword("DESC") {
+			direction = "DESC"
+		} else {
+			p.acceptKeyword("ASC")
+		}
+		order = append(order, column.name+" "+direction)
+		if !p.accept(",") {
+			break
+		}
+	}
+	if !p.done() {
+		return nil, fmt.Errorf("unexpected %q in $order", p.peek().text)
+	}
+	return order, nil
+}
+
+type sodaTokenKind int
+
+const (
+	sodaEOF sodaTokenKind = iota
+	sodaIdent
+	sodaString
+	sodaNumber
+	sodaSymbol
+)
+
+type sodaToken struct {
+	kind sodaTokenKind
+	text string
+}
+
+// sodaParser is a recursive descent parser for SoQL expressions. The SQL it
+// produces only contains validated column names, keywords and placeholders;
+// literals are collected in args.
+type sodaParser struct {
+	tokens []sodaToken
+	pos    int
+	args   []any
+}
+
+func newSodaParser(s string) (*sodaParser, error) {
+	tokens, err := tokenizeSoql(s)
+	if err != nil {
+		return nil, err
+	}
+	return &sodaParser{tokens: tokens}, nil
+}
+
+func tokenizeSoql(s string) ([]sodaToken, error) {
+	var tokens []sodaToken
+	runes := []rune(s)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '\'':
+			// a quote inside a string is written as two quotes
+			var b strings.Builder
+			i++
+			for {
+				if i >= len(runes) {
+					return nil, fmt.Errorf("unterminated string")
+				}
+				if runes[i] == '\'' {
+					if i+1 < len(runes) && runes[i+1] == '\'' {
+						b.WriteRune('\'')
+						i += 2
+						continue
+					}
+					i++
+					break
+				}
+				b.WriteRune(runes[i])
+				i++
+			}
+			tokens = append(tokens, sodaToken{sodaString, b.String()})
+		case unicode.IsDigit(r) || r == '-' && i+1 < len(runes) && unicode.IsDigit(runes[i+1]):
+			start := i
+			i++
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, sodaToken{sodaNumber, string(runes[start:i])})
+		case unicode.IsLetter(r) || r == '_' || r == ':':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_' || runes[i] == ':') {
+				i++
+			}
+			tokens = append(tokens, sodaToken{sodaIdent, string(runes[start:i])})
+		default:
+			symbol := string(r)
+			if i+1 < len(runes) {
+				if two := string(runes[i : i+2]); two == "<=" || two == ">=" || two == "!=" || two == "<>" {
+					symbol = two
+				}
+			}
+			if !strings.Contains("()=<>,*", symbol[:1]) || symbol == "!" {
+				return nil, fmt.Errorf("unexpected character %q", r)
+			}
+			tokens = append(tokens, sodaToken{sodaSymbol, symbol})
+			i += len(symbol)
+		}
+	}
+	return tokens, nil
+}
+
+func (p *sodaParser) peek() sodaToken {
+	if p.pos >= len(p.tokens) {
+		return sodaToken{kind: sodaEOF}
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *sodaParser) next() sodaToken {
+	token := p.peek()
+	if token.kind != sodaEOF {
+		p.pos++
+	}
+	return token
+}
+
+func (p *sodaParser) done() bool {
+	return p.peek().kind == sodaEOF
+}
+
+// accept consumes the next token if it is the given symbol.
+func (p *sodaParser) accept(symbol string) bool {
+	if token := p.peek(); token.kind == sodaSymbol && token.text == symbol {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// acceptKeyword consumes the next token if it is the given keyword, in any
+// case.
+func (p *sodaParser) acceptKeyword(keyword string) bool {
+	if token := p.peek(); token.kind == sodaIdent && strings.EqualFold(token.text, keyword) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *sodaParser) expect(symbol string) error {
+	if !p.accept(symbol) {
+		return fmt.Errorf("expected %q, got %q", symbol, p.peek().text)
+	}
+	return nil
+}
+
+func (p *sodaParser) parseColumn() (voertuigColumn, error) {
+	token := p.next()
+	if token.kind != sodaIdent {
+		return voertuigColumn{}, fmt.Errorf("expected column name, got %q", token.text)
+	}
+	column, ok := findVoertuigColumn(strings.ToLower(token.text))
+	if !ok {
+		return voertuigColumn{}, fmt.Errorf("no such column: %s", token.text)
+	}
+	return column, nil
+}
+
+// parseLiteral reads a string or number literal and adds it to the
+// arguments, converted for column.
+func (p *sodaParser) parseLiteral(column voertuigColumn) error {
+	token := p.next()
+	if token.kind != sodaString && token.kind != sodaNumber {
+		return fmt.Errorf("expected literal, got %q", token.text)
+	}
+	value, err := sodaLiteral(column, token.text)
+	if err != nil {
+		return err
+	}
+	p.args = append(p.args, value)
+	return nil
+}
+
+func (p *sodaParser) parseWhere() (string, error) {
+	condition, err := p.parseOr()
+	if err != nil {
+		return "", err
+	}
+	if !p.done() {
+		return "", fmt.Errorf("unexpected %q in $where", p.peek().text)
+	}
+	return condition, nil
+}
+
+func (p *sodaParser) parseOr() (string, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return "", err
+	}
+	for p.acceptKeyword("OR") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return "", err
+		}
+		left = left + " OR " + right
+	}
+	return left, nil
+}
+
+func (p *sodaParser) parseAnd() (string, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return "", err
+	}
+	for p.acceptKeyword("AND") {
+		right, err := p.parseNot()
+		if err != nil {
+			return "", err
+		}
+		left = left + " AND " + right
+	}
+	return left, nil
+}
+
+func (p *sodaParser) parseNot() (string, error) {
+	if p.acceptKeyword("NOT") {
+		condition, err := p.parseNot()
+		if err != nil {
+			return "", err
+		}
+		return "NOT " + condition, nil
+	}
+	if p.accept("(") {
+		condition, err := p.parseOr()
+		if err != nil {
+			return "", err
+		}
+		if err := p.expect(")"); err != nil {
+			return "", err
+		}
+		return "(" + condition + ")", nil
+	}
+	return p.parseComparison()
+}
+
+// parseComparison parses column <op> literal, column IS [NOT] NULL,
+// column [NOT] IN (...), column [NOT] BETWEEN a AND b, column [NOT] LIKE
+// 'pattern' and starts_with(column, 'prefix').
+func (p *sodaParser) parseComparison() (string, error) {
+	if token := p.peek(); token.kind == sodaIdent && strings.EqualFold(token.text, "starts_with") {
+		p.pos++
+		if err := p.expect("("); err != nil {
+			return "", err
+		}
+		column, err := p.parseColumn()
+		if err != nil {
+			return "", err
+		}
+		if err := p.expect(","); err != nil {
+			return "", err
+		}
+		prefix := p.next()
+		if prefix.kind != sodaString {
+			return "", fmt.Errorf("starts_with expects a string, got %q", prefix.text)
+		}
+		if err := p.expect(")"); err != nil {
+			return "", err
+		}
+		// ! as escape character works the same in MySQL and SQLite
+		escaped := strings.NewReplacer(`!`, `!!`, `%`, `!%`, `_`, `!_`).Replace(prefix.text)
+		p.args = append(p.args, escaped+"%")
+		return column.name + " LIKE ? ESCAPE '!'", nil
+	}
+
+	column, err := p.parseColumn()
+	if err != nil {
+		return "", err
+	}
+
+	if p.acceptKeyword("IS") {
+		if p.acceptKeyword("NOT") {
+			if !p.acceptKeyword("NULL") {
+				return "", fmt.Errorf("expected NULL after IS NOT")
+			}
+			return column.name + " IS NOT NULL", nil
+		}
+		if !p.acceptKeyword("NULL") {
+			return "", fmt.Errorf("expected NULL after IS")
+		}
+		return column.name + " IS NULL", nil
+	}
+
+	not := ""
+	if p.acceptKeyword("NOT") {
+		not = "NOT "
+	}
+	switch {
+	case p.acceptKeyword("IN"):
+		if err := p.expect("("); err != nil {
+			return "", err
+		}
+		n := 0
+		for {
+			if err := p.parseLiteral(column); err != nil {
+				return "", err
+			}
+			n++
+			if !p.accept(",") {
+				break
+			}
+		}
+		if err := p.expect(")"); err != nil {
+			return "", err
+		}
+		return column.name + " " + not + "IN (" + placeholders(n) + ")", nil
+	case p.acceptKeyword("BETWEEN"):
+		if err := p.parseLiteral(column); err != nil {
+			return "", err
+		}
+		if !p.acceptKeyword("AND") {
+			return "", fmt.Errorf("expected AND in BETWEEN")
+		}
+		if err := p.parseLiteral(column); err != nil {
+			return "", err
+		}
+		return column.name + " " + not + "BETWEEN ? AND ?", nil
+	case p.acceptKeyword("LIKE"):
+		pattern := p.next()
+		if pattern.kind != sodaString {
+			return "", fmt.Errorf("LIKE expects a string, got %q", pattern.text)
+		}
+		p.args = append(p.args, pattern.text)
+		return column.name + " " + not + "LIKE ?", nil
+	case not != "":
+		return "", fmt.Errorf("expected IN, BETWEEN or LIKE after NOT")
+	}
+
+	operator := p.next()
+	switch operator.text {
+	case "=", "!=", "<>", "<", "<=", ">", ">=":
+	default:
+		return "", fmt.Errorf("expected operator after %s, got %q", column.name, operator.text)
+	}
+	if operator.kind != sodaSymbol {
+		return "", fmt.Errorf("expected operator after %s, got %q", column.name, operator.text)
+	}
+	if err := p.parseLiteral(column); err != nil {
+		return "", err
+	}
+	return column.name + " " + operator.text + " ?", nil
+}
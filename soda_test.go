@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseSodaQuery(t *testing.T) {
+	tests := []struct {
+		values url.Values
+		query  string
+		args   []any
+	}{
+		{
+			url.Values{"$select": {"kenteken, merk AS brand"}, "$where": {"merk = 'TESLA' AND catalogusprijs > 50000"}},
+			"SELECT kenteken, merk FROM voertuigen WHERE (merk = ? AND catalogusprijs > ?) ORDER BY kenteken LIMIT 1000 OFFSET 0",
+			[]any{"TESLA", 50000},
+		},
+		{
+			url.Values{"$select": {"kenteken"}, "$where": {"starts_with(handelsbenaming, 'MODEL') OR NOT (merk = 'it''s')"}},
+			"SELECT kenteken FROM voertuigen WHERE (handelsbenaming LIKE ? ESCAPE '!' OR NOT (merk = ?)) ORDER BY kenteken LIMIT 1000 OFFSET 0",
+			[]any{"MODEL%", "it's"},
+		},
+		{
+			url.Values{"$select": {"kenteken"}, "$where": {"merk IN ('A', 'B') AND eerste_kleur IS NULL"}},
+			"SELECT kenteken FROM voertuigen WHERE (merk IN (?, ?) AND eerste_kleur IS NULL) ORDER BY kenteken LIMIT 1000 OFFSET 0",
+			[]any{"A", "B"},
+		},
+		{
+			url.Values{"$select": {"kenteken"}, "merk": {"BMW"}, "$order": {"datum_eerste_toelating DESC"}, "$limit": {"10"}, "$offset": {"20"}},
+			"SELECT kenteken FROM voertuigen WHERE merk = ? ORDER BY datum_eerste_toelating DESC, kenteken LIMIT 10 OFFSET 20",
+			[]any{"BMW"},
+		},
+		{
+			url.Values{"$select": {"kenteken"}, "$limit": {"999999"}},
+			"SELECT kenteken FROM voertuigen ORDER BY kenteken LIMIT 50000 OFFSET 0",
+			nil,
+		},
+	}
+	for _, test := range tests {
+		q, err := parseSodaQuery(test.values, defaultSodaMaxLimit)
+		if err != nil {
+			t.Errorf("parseSodaQuery(%v) returned %v", test.values, err)
+			continue
+		}
+		query, args := q.sql()
+		// numeric literals keep the type of their column, compare the values
+		if query != test.query || fmt.Sprint(args) != fmt.Sprint(test.args) {
+			t.Errorf("parseSodaQuery(%v) = %q %#v, want %q %#v", test.values, query, args, test.query, test.args)
+		}
+	}
+}
+
+func TestParseSodaQueryAllColumns(t *testing.T) {
+	q, err := parseSodaQuery(url.Values{}, defaultSodaMaxLimit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query, _ := q.sql()
+	if !strings.HasPrefix(query, "SELECT "+voertuigColumnList()+" FROM voertuigen") {
+		t.Errorf("got %q", query)
+	}
+}
+
+func TestParseSodaQueryErrors(t *testing.T) {
+	tests := []url.Values{
+		{"$where": {"merk = 'x'; DROP TABLE voertuigen"}},
+		{"$where": {"merk = 'x' OR 1 = 1"}},
+		{"$where": {"(merk = 'x'"}},
+		{"$where": {`merk = "x"`}},
+		{"$where": {"nope = 1"}},
+		{"$select": {"kenteken, count(*)"}},
+		{"$order": {"merk; DROP TABLE voertuigen"}},
+		{"$group": {"merk"}},
+		{"$limit": {"-1"}},
+		{"$offset": {"x"}},
+		{"nope": {"1"}},
+		{"aantal_zitplaatsen": {"vijf"}},
+	}
+	for _, values := range tests {
+		if _, err := parseSodaQuery(values, defaultSodaMaxLimit); err == nil {
+			t.Errorf("parseSodaQuery(%v) did not return an error", values)
+		}
+	}
+}
+
+func TestAPISoda(t *testing.T) {
+	server := newTestServer(t)
+
+	var rows []map[string]string
+	getJSON(t, server, "/resource/m9d7-ebf2.json?merk=TESLA&$select=kenteken,eerste_kleur%20AS%20kleur,datum_eerste_toelating,catalogusprijs", &rows)
+	want := []map[string]string{
+		{"kenteken": "GB123D", "kleur": "WIT", "datum_eerste_toelating": "20210301", "catalogusprijs": "55000"},
+		{"kenteken": "XX123B", "kleur": "BLAUW", "datum_eerste_toelating": "20200115", "catalogusprijs": "45000"},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("got %v, want %v", rows, want)
+	}
+
+	status, body := request(t, server, http.MethodGet, "/resource/m9d7-ebf2.json?$where=merk%20%3D%201%3B", "", "")
+	var sodaErr map[string]any
+	if err := json.Unmarshal([]byte(body), &sodaErr); status != http.StatusBadRequest || err != nil || sodaErr["code"] != "query.compiler.malformed" || sodaErr["error"] != true {
+		t.Errorf("malformed $where returned %d: %s", status, body)
+	}
+}
@@ -0,0 +1,293 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"regexp"
+	"strconv"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+const defaultSQLiteFile = "kentekens.db"
+
+// sqlitePragmas tune SQLite for bulk imports next to readers: WAL lets the
+// API read while an import writes, synchronous NORMAL only syncs at
+// checkpoints and the page cache is 64 MB.
+const sqlitePragmas = "_pragma=journal_mode(WAL)&_pragma=synchronous(NORMAL)&_pragma=busy_timeout(5000)&_pragma=temp_store(MEMORY)&_pragma=cache_size(-65536)&_time_format=sqlite"
+
+// sqliteStore is the VehicleStore on a SQLite file, for running the importer
+// and API without a database server.
+type sqliteStore struct {
+	db *sql.DB
+	transactionalSwap
+}
+
+// opens the SQLite file, applying the migrations when the file is new
+func openSQLiteStore(path string) (*sqliteStore, error) {
+	db, err := sql.Open("sqlite", path+"?"+sqlitePragmas)
+	if err != nil {
+		return nil, err
+	}
+	// SQLite has a single writer, one connection keeps the import batches
+	// from failing on a locked database
+	db.SetMaxOpenConns(1)
+
+	store := &sqliteStore{db: db, transactionalSwap: transactionalSwap{db, sqliteListTables}}
+	var tables int
+	if err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name IN (?, 'schema_version')", voertuigenTable).Scan(&tables); err != nil {
+		return nil, err
+	}
+	if tables == 0 {
+		log.Printf("Creating schema in %s", path)
+		if _, err := migrateUp(store); err != nil {
+			return nil, err
+		}
+	}
+	return store, nil
+}
+
+// BulkWrite keeps voertuigen_history in step with the batch, see
+// writeWithHistory.
+func (s *sqliteStore) BulkWrite(table string, records []RDWRecord, mode string, snapshot time.Time) (writeResult, error) {
+	return writeWithHistory(s.db, table, mode, records, snapshot, func() (writeResult, error) {
+		return s.bulkWrite(table, records, mode, snapshot)
+	})
+}
+
+// bulkWrite writes the batch within a single transaction. SQLite does not
+// tell inserts and updates apart in the affected rows, so an upsert looks up
+// the stored row hashes first.
+func (s *sqliteStore) bulkWrite(table string, records []RDWRecord, mode string, snapshot time.Time) (writeResult, error) {
+	if mode == "delta" {
+		return writeDelta(s.db, insertVoertuigSQL(voertuigenTable)+onConflictUpdateSQL(), records, snapshot)
+	}
+
+	var result writeResult
+	query := insertVoertuigSQL(table)
+	stored := map[string]storedVersion{}
+	if mode == "upsert" {
+		query = insertVoertuigSQL(table) + onConflictUpdateSQL()
+		kentekens := make([]string, len(records))
+		for i := range records {
+			kentekens[i] = records[i].Kenteken
+		}
+		var err error
+		if stored, err = getStoredVersions(s.db, table, kentekens); err != nil {
+			return result, err
+		}
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return result, err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(query)
+	if err != nil {
+		return result, err
+	}
+	defer stmt.Close()
+
+	for _, record := range records {
+		if _, err := stmt.Exec(voertuigArgs(&record, snapshot)...); err != nil {
+			log.Println("Error inserting record ", err)
+			result.failed++
+			continue
+		}
+
+		result.count(stored, &record)
+	}
+
+	return result, tx.Commit()
+}
+
+// WriteRows writes a batch of dataset rows within a single transaction, with
+// one prepared statement like bulkWrite.
+func (s *sqliteStore) WriteRows(table string, columns []string, rows [][]any) (int64, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(insertRowsSQL(table, columns, 1))
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	var failed int64
+	for _, row := range rows {
+		if _, err := stmt.Exec(row...); err != nil {
+			log.Println("Error inserting record ", err)
+			failed++
+		}
+	}
+	return failed, tx.Commit()
+}
+
+func (s *sqliteStore) Get(kenteken string, columns projection) (RDWRecord, error) {
+	return getVoertuig(s.db, kenteken, columns)
+}
+
+func (s *sqliteStore) GetMany(kentekens []string, columns projection) (map[string]RDWRecord, error) {
+	return getVoertuigen(s.db, kentekens, columns)
+}
+
+func (s *sqliteStore) Search(q *searchQuery) ([]RDWRecord, string, error) {
+	return searchVoertuigen(s.db, q)
+}
+
+func (s *sqliteStore) Stats() (vehicleStats, error) {
+	return getVehicleStats(s.db)
+}
+
+func (s *sqliteStore) DatasetRows(dataset rdwDataset, column, value string) ([]map[string]any, error) {
+	return getDatasetRows(s.db, dataset, column, value)
+}
+
+func (s *sqliteStore) History(kenteken string) ([]historyVersion, error) {
+	return getVoertuigHistory(s.db, kenteken)
+}
+
+func (s *sqliteStore) Recalls(kenteken string) (voertuigRecalls, error) {
+	return getVoertuigRecalls(s.db, kenteken)
+}
+
+func (s *sqliteStore) Keuringen(kenteken string) ([]keuring, error) {
+	return getVoertuigKeuringen(s.db, kenteken)
+}
+
+func (s *sqliteStore) Soda(q *sodaQuery) ([]map[string]string, error) {
+	return querySoda(s.db, q)
+}
+
+func (s *sqliteStore) ImportRuns(limit int) ([]importRun, error) {
+	return getImportRuns(s.db, limit)
+}
+
+func (s *sqliteStore) LastSucceededImportRun() (importRun, error) {
+	return getLastSucceededImportRun(s.db)
+}
+
+func (s *sqliteStore) MarkRemoved(snapshot time.Time, seen []string) (int64, error) {
+	return markRemoved(s.db, snapshot, seen)
+}
+
+func (s *sqliteStore) ImportState(table string) (importState, error) {
+	return getImportState(s.db, table)
+}
+
+func (s *sqliteStore) SaveImportState(state importState) error {
+	return saveImportState(s.db, state)
+}
+
+func (s *sqliteStore) DeleteImportState(table string) error {
+	return deleteImportState(s.db, table)
+}
+
+func (s *sqliteStore) StartImportRun(run importRun) (int64, error) {
+	return insertImportRun(s.db, run)
+}
+
+func (s *sqliteStore) FinishImportRun(run importRun) error {
+	return finishImportRun(s.db, run)
+}
+
+func (s *sqliteStore) DropStaging(staging string) error {
+	_, err := s.db.Exec("DROP TABLE IF EXISTS " + staging)
+	return err
+}
+
+func (s *sqliteStore) Close() error {
+	return s.db.Close()
+}
+
+// sqliteListTables selects the tables with a name LIKE its argument.
+const sqliteListTables = "SELECT name FROM sqlite_master WHERE type = 'table' AND name LIKE ?"
+
+var (
+	sqliteCreateTable = regexp.MustCompile(`^CREATE TABLE\s+"?\w+"?`)
+	sqliteCreateIndex = regexp.MustCompile(`^CREATE (UNIQUE )?INDEX\s+"?(\w+?)(__\d+)?"?\s+ON\s+"?\w+"?`)
+)
+
+// CreateStaging recreates the staging table from the CREATE statements of
+// table and its indexes in sqlite_master. SQLite keeps index names when a
+// table is renamed, so the staging indexes get a unique __<nanoseconds>
+// suffix in place of the previous one.
+func (s *sqliteStore) CreateStaging(table string) (string, error) {
+	staging := stagingTableName(table)
+	if _, err := s.db.Exec("DROP TABLE IF EXISTS " + staging); err != nil {
+		return "", err
+	}
+
+	var create string
+	if err := s.db.QueryRow("SELECT sql FROM sqlite_master WHERE type = 'table' AND name = ?", table).Scan(&create); err != nil {
+		return "", err
+	}
+	statements := []string{sqliteCreateTable.ReplaceAllString(create, "CREATE TABLE "+staging)}
+
+	rows, err := s.db.Query("SELECT sql FROM sqlite_master WHERE type = 'index' AND tbl_name = ? AND sql IS NOT NULL", table)
+	if err != nil {
+		return "", err
+	}
+	suffix := "__" + strconv.FormatInt(time.Now().UnixNano(), 10)
+	for rows.Next() {
+		var index string
+		if err := rows.Scan(&index); err != nil {
+			rows.Close()
+			return "", err
+		}
+		statements = append(statements, sqliteCreateIndex.ReplaceAllString(index, "CREATE ${1}INDEX ${2}"+suffix+" ON "+staging))
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+
+	for _, statement := range statements {
+		if _, err := s.db.Exec(statement); err != nil {
+			return "", err
+		}
+	}
+	return staging, nil
+}
+
+func (s *sqliteStore) ValidateStaging(table, staging string, written int64, required []string) error {
+	return validateStagingTable(s.db, table, staging, written, required)
+}
+
+func (s *sqliteStore) MigrationsDir() string {
+	return "sqlite"
+}
+
+// LockMigrations starts an immediate transaction, which holds the write lock
+// of the file until it ends. The migrations of a run are applied in it, so
+// they are all applied or none is.
+func (s *sqliteStore) LockMigrations(ctx context.Context, conn *sql.Conn) (func(commit bool) error, error) {
+	if _, err := conn.ExecContext(ctx, "BEGIN IMMEDIATE"); err != nil {
+		return nil, err
+	}
+	return func(commit bool) error {
+		end := "ROLLBACK"
+		if commit {
+			end = "COMMIT"
+		}
+		_, err := conn.ExecContext(context.Background(), end)
+		return err
+	}, nil
+}
+
+// TransactionalDDL is false because LockMigrations already runs the
+// migrations in a transaction.
+func (s *sqliteStore) TransactionalDDL() bool {
+	return false
+}
+
+func (s *sqliteStore) Conn(ctx context.Context) (*sql.Conn, error) {
+	return s.db.Conn(ctx)
+}
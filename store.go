@@ -0,0 +1,175 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// VehicleStore stores the voertuigen and the tables around them. The importer
+// and the API only reach the database through it, so another backend or a
+// fake can be plugged in.
+type VehicleStore interface {
+	// BulkWrite writes a batch of records into table, voertuigen or a staging
+	// table. mode is insert (a duplicate kenteken fails), upsert (existing
+	// vehicles are updated) or delta (only added and changed vehicles are
+	// written and recorded, unchanged ones get the snapshot date). Rows that
+	// fail are logged and counted, an error means the batch was not written.
+	// Once the delta mode started the history, an insert or upsert into
+	// voertuigen records its vehicles there too.
+	BulkWrite(table string, records []RDWRecord, mode string, snapshot time.Time) (writeResult, error)
+
+	// Get returns the columns of one vehicle, or sql.ErrNoRows.
+	Get(kenteken string, columns projection) (RDWRecord, error)
+
+	// GetMany returns the columns of the vehicles that exist, by kenteken.
+	GetMany(kentekens []string, columns projection) (map[string]RDWRecord, error)
+
+	// Search returns one page of q and the cursor of the next page, which is
+	// empty on the last page.
+	Search(q *searchQuery) ([]RDWRecord, string, error)
+
+	Stats() (vehicleStats, error)
+
+	// DatasetRows returns the rows of a linked dataset where column equals
+	// value.
+	DatasetRows(dataset rdwDataset, column, value string) ([]map[string]any, error)
+
+	// History returns the versions of a vehicle, oldest first.
+	History(kenteken string) ([]historyVersion, error)
+
+	// Recalls returns the open and closed recalls of a vehicle.
+	Recalls(kenteken string) (voertuigRecalls, error)
+
+	// Keuringen returns the APK inspections of a vehicle, oldest first.
+	Keuringen(kenteken string) ([]keuring, error)
+
+	// Soda runs a parsed SODA query on voertuigen.
+	Soda(q *sodaQuery) ([]map[string]string, error)
+
+	// ImportRuns returns the newest limit import runs, newest first.
+	ImportRuns(limit int) ([]importRun, error)
+
+	// LastSucceededImportRun returns the newest successful import run, or
+	// sql.ErrNoRows.
+	LastSucceededImportRun() (importRun, error)
+
+	// Close closes the database.
+	Close() error
+}
+
+// importStore is what the importer needs besides VehicleStore: staging
+// tables for full imports, removal tracking for delta imports, the dataset
+// rows and the bookkeeping in import_state and import_runs.
+type importStore interface {
+	VehicleStore
+
+	// CreateStaging (re)creates an empty copy of table and returns its name.
+	CreateStaging(table string) (string, error)
+
+	// ValidateStaging checks a staging table before it replaces table, see
+	// validateStagingTable.
+	ValidateStaging(table, staging string, written int64, required []string) error
+
+	// SwapStaging replaces table with staging and returns the name the
+	// previous table is kept under. A swap of voertuigen records the vehicles
+	// it adds, changes and removes in the history.
+	SwapStaging(table, staging string) (string, error)
+
+	// Rollback puts the newest backup of table back in place and returns the
+	// backup and the name the replaced table is kept under. Like a swap, a
+	// rollback of voertuigen is recorded in the history.
+	Rollback(table string) (string, string, error)
+
+	// WriteRows writes a batch of dataset rows, values for columns, into
+	// table and returns how many rows failed. Rows that fail are logged, an
+	// error means the batch was not written.
+	WriteRows(table string, columns []string, rows [][]any) (int64, error)
+
+	// DropStaging drops a staging table that is not going to be swapped in.
+	DropStaging(staging string) error
+
+	// MarkRemoved marks every vehicle that is not part of snapshot as removed.
+	// seen are vehicles that are in the snapshot but were not written, like
+	// rejected rows.
+	MarkRemoved(snapshot time.Time, seen []string) (int64, error)
+
+	// ImportState returns the state of the unfinished import into table, or
+	// sql.ErrNoRows.
+	ImportState(table string) (importState, error)
+
+	// SaveImportState replaces the state of the import into state.table.
+	SaveImportState(state importState) error
+
+	// DeleteImportState deletes the state of the import into table.
+	DeleteImportState(table string) error
+
+	// StartImportRun records run as running and returns its id.
+	StartImportRun(run importRun) (int64, error)
+
+	// FinishImportRun records the counts and outcome of the run with run.ID.
+	FinishImportRun(run importRun) error
+}
+
+// openStore opens the database selected with DB_DRIVER: mysql (the default),
+// sqlite, a file at SQLITE_FILE (default kentekens.db), or postgres.
+func openStore() (importStore, error) {
+	switch driver := getEnvVar("DB_DRIVER"); driver {
+	case "", "mysql":
+		store, err := openMySQLStore()
+		if err != nil {
+			return nil, err
+		}
+		return store, nil
+	case "sqlite":
+		path := getEnvVar("SQLITE_FILE")
+		if path == "" {
+			path = defaultSQLiteFile
+		}
+		store, err := openSQLiteStore(path)
+		if err != nil {
+			return nil, err
+		}
+		return store, nil
+	case "postgres":
+		store, err := openPostgresStore()
+		if err != nil {
+			return nil, err
+		}
+		return store, nil
+	default:
+		return nil, fmt.Errorf("unknown DB_DRIVER %q, expected mysql, sqlite or postgres", driver)
+	}
+}
+
+// writeResult counts what happened to the rows of one BulkWrite.
+type writeResult struct {
+	inserted  int64
+	updated   int64
+	unchanged int64
+	failed    int64
+}
+
+// count adds a written record to the result by the row hash stored before
+// the batch: new, updated when the hash differs or the vehicle was removed,
+// unchanged otherwise. A new snapshot date alone is no change. stored is
+// updated so a kenteken that occurs twice in a batch is only new once.
+func (r *writeResult) count(stored map[string]storedVersion, record *RDWRecord) {
+	hash := record.hash()
+	version, ok := stored[record.Kenteken]
+	switch {
+	case !ok:
+		r.inserted++
+	case version.hash == hash && !version.removed:
+		r.unchanged++
+	default:
+		r.updated++
+	}
+	stored[record.Kenteken] = storedVersion{hash: hash, snapshot: version.snapshot}
+}
+
+// vehicleStats describes the contents of the store.
+type vehicleStats struct {
+	Total        int64    `json:"total"`
+	Removed      int64    `json:"removed"`
+	LastSnapshot NullDate `json:"last_snapshot"`
+}
@@ -0,0 +1,321 @@
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	voertuigenTable = "voertuigen"
+
+	defaultBackupTables   = 2
+	defaultSwapMinPercent = 90
+)
+
+func stagingTableName(table string) string {
+	return table + "_staging"
+}
+
+func backupTablePrefix(table string) string {
+	return table + "_backup_"
+}
+
+// tableTimestamp returns the current time as yyyymmddhhmmss followed by the
+// milliseconds, for the names of backup and rolled back tables. Two swaps or
+// a swap and a rollback within one second get different names, and the
+// longest name, voertuigen_carrosserie_specifiek_rolled_back_<timestamp>,
+// still fits in the 63 characters of PostgreSQL.
+func tableTimestamp() string {
+	return strings.Replace(time.Now().UTC().Format("20060102150405.000"), ".", "", 1)
+}
+
+// createStagingTable (re)creates an empty copy of table to import into.
+func createStagingTable(db *sql.DB, table string) (string, error) {
+	staging := stagingTableName(table)
+	if _, err := db.Exec("DROP TABLE IF EXISTS " + staging); err != nil {
+		return "", err
+	}
+	if _, err := db.Exec("CREATE TABLE " + staging + " LIKE " + table); err != nil {
+		return "", err
+	}
+	return staging, nil
+}
+
+// validateStagingTable checks the staging table before it replaces the live
+// table: it must hold every written row, have no rows where one of the
+// required columns is empty and not shrink below SWAP_MIN_PERCENT (default 90)
+// of the live table.
+func validateStagingTable(db *sql.DB, table, staging string, written int64, required []string) error {
+	var staged int64
+	if err := db.QueryRow("SELECT COUNT(*) FROM " + staging).Scan(&staged); err != nil {
+		return err
+	}
+	if staged == 0 {
+		return fmt.Errorf("%s is empty", staging)
+	}
+	if staged != written {
+		return fmt.Errorf("%s holds %d rows but %d were written", staging, staged, written)
+	}
+
+	conditions := make([]string, len(required))
+	for i, column := range required {
+		conditions[i] = column + " IS NULL OR " + column + " = ''"
+	}
+	var incomplete int64
+	err := db.QueryRow("SELECT COUNT(*) FROM " + staging + " WHERE " + strings.Join(conditions, " OR ")).Scan(&incomplete)
+	if err != nil {
+		return err
+	}
+	if incomplete > 0 {
+		return fmt.Errorf("%s has %d rows without %s", staging, incomplete, strings.Join(required, " or "))
+	}
+
+	var live int64
+	if err := db.QueryRow("SELECT COUNT(*) FROM " + table).Scan(&live); err != nil {
+		return err
+	}
+	minPercent := int64(defaultSwapMinPercent)
+	if getEnvVar("SWAP_MIN_PERCENT") != "" {
+		minPercent = int64(getIntEnvVar("SWAP_MIN_PERCENT"))
+	}
+	if staged*100 < live*minPercent {
+		return fmt.Errorf("%s holds %d rows, less than %d%% of the %d rows in %s", staging, staged, minPercent, live, table)
+	}
+
+	return nil
+}
+
+// swapStagingTable atomically replaces table with the staging table, drops
+// backups beyond BACKUP_TABLES and returns the name under which the previous
+// table was kept.
+func swapStagingTable(db *sql.DB, table, staging string) (string, error) {
+	backup := backupTablePrefix(table) + tableTimestamp()
+	_, err := db.Exec("RENAME TABLE " + table + " TO " + backup + ", " + staging + " TO " + table)
+	if err != nil {
+		return "", err
+	}
+	if err := recordSwapHistory(db, table, backup); err != nil {
+		return backup, err
+	}
+
+	backups, err := listBackupTables(db, table)
+	if err == nil {
+		err = pruneBackupTables(db, backups)
+	}
+	if err != nil {
+		log.Println("Error removing old backup tables ", err)
+	}
+	return backup, nil
+}
+
+// pruneBackupTables drops all but the newest BACKUP_TABLES (default 2) of
+// backups, which are sorted newest first.
+func pruneBackupTables(db *sql.DB, backups []string) error {
+	keep := defaultBackupTables
+	if getEnvVar("BACKUP_TABLES") != "" {
+		keep = getIntEnvVar("BACKUP_TABLES")
+	}
+
+	for i := keep; i < len(backups); i++ {
+		log.Printf("Dropping old backup table %s", backups[i])
+		if _, err := db.Exec("DROP TABLE " + backups[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// listBackupTables returns the backup tables of table, newest first.
+func listBackupTables(db *sql.DB, table string) ([]string, error) {
+	rows, err := db.Query("SHOW TABLES LIKE '" + backupTablePrefix(table) + "%'")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return backupTables(table, names), rows.Err()
+}
+
+// backupTables returns the names that are backups of table, newest first.
+// LIKE treats _ as a wildcard, so only names with a timestamp are kept. The
+// timestamps of older backups have no milliseconds, they still sort by time
+// because the seconds come first.
+func backupTables(table string, names []string) []string {
+	prefix := backupTablePrefix(table)
+	var backups []string
+	for _, name := range names {
+		timestamp, ok := strings.CutPrefix(name, prefix)
+		if ok && len(timestamp) >= 14 && strings.Trim(timestamp, "0123456789") == "" {
+			backups = append(backups, name)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(backups)))
+	return backups
+}
+
+// transactionalSwap implements SwapStaging and Rollback for databases that
+// rename one table per statement, but can do so within a transaction.
+type transactionalSwap struct {
+	db *sql.DB
+	// listTables selects the names of the tables LIKE its argument
+	listTables string
+}
+
+func (t transactionalSwap) SwapStaging(table, staging string) (string, error) {
+	backup := backupTablePrefix(table) + tableTimestamp()
+	if err := t.renameTables(table, backup, staging, table); err != nil {
+		return "", err
+	}
+	if err := recordSwapHistory(t.db, table, backup); err != nil {
+		return backup, err
+	}
+
+	backups, err := t.listBackupTables(table)
+	if err == nil {
+		err = pruneBackupTables(t.db, backups)
+	}
+	if err != nil {
+		log.Println("Error removing old backup tables ", err)
+	}
+	return backup, nil
+}
+
+func (t transactionalSwap) Rollback(table string) (string, string, error) {
+	backups, err := t.listBackupTables(table)
+	if err != nil {
+		return "", "", err
+	}
+	if len(backups) == 0 {
+		return "", "", errNoBackup(table)
+	}
+
+	rolledBack := table + "_rolled_back_" + tableTimestamp()
+	if err := t.renameTables(table, rolledBack, backups[0], table); err != nil {
+		return "", "", err
+	}
+	return backups[0], rolledBack, recordRollbackHistory(t.db, table, rolledBack)
+}
+
+// renameTables renames from to to and then other to otherTo, in one
+// transaction.
+func (t transactionalSwap) renameTables(from, to, other, otherTo string) error {
+	tx, err := t.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("ALTER TABLE " + from + " RENAME TO " + to); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("ALTER TABLE " + other + " RENAME TO " + otherTo); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (t transactionalSwap) listBackupTables(table string) ([]string, error) {
+	rows, err := t.db.Query(t.listTables, backupTablePrefix(table)+"%")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return backupTables(table, names), rows.Err()
+}
+
+func errNoBackup(table string) error {
+	return fmt.Errorf("there is no backup of %s to roll back to", table)
+}
+
+// runRollback puts the newest backup table back in place of -table (default
+// voertuigen). The table it replaces is kept as <table>_rolled_back_<timestamp>.
+// A rollback of voertuigen closes and opens the history versions of the
+// vehicles it changes.
+func runRollback(args []string) {
+	flags := flag.NewFlagSet("rollback", flag.ExitOnError)
+	table := flags.String("table", voertuigenTable, "table to roll back to its newest backup")
+	flags.Parse(args)
+
+	store, err := openStore()
+	if err != nil {
+		log.Fatal("Error connecting to the database ", err)
+	}
+
+	backup, rolledBack, err := store.Rollback(*table)
+	if err != nil {
+		log.Fatal("Error rolling back ", err)
+	}
+	log.Printf("Restored %s as %s, the replaced table is kept as %s", backup, *table, rolledBack)
+}
+
+// rollbackTable renames the newest backup of table into its place and returns
+// the backup and the name the replaced table is kept under.
+func rollbackTable(db *sql.DB, table string) (string, string, error) {
+	backups, err := listBackupTables(db, table)
+	if err != nil {
+		return "", "", err
+	}
+	if len(backups) == 0 {
+		return "", "", errNoBackup(table)
+	}
+
+	rolledBack := table + "_rolled_back_" + tableTimestamp()
+	_, err = db.Exec("RENAME TABLE " + table + " TO " + rolledBack + ", " + backups[0] + " TO " + table)
+	if err != nil {
+		return "", "", err
+	}
+	return backups[0], rolledBack, recordRollbackHistory(db, table, rolledBack)
+}
+
+// recordSwapHistory records what the swap of table changed in the history,
+// valid from the snapshot date of the table swapped in. The swap itself is
+// done when this fails, so the backup is not pruned and the error says so.
+func recordSwapHistory(db *sql.DB, table, backup string) error {
+	if table != voertuigenTable {
+		return nil
+	}
+	date, err := lastSnapshotDate(db)
+	if err == nil {
+		err = recordReplacedTable(db, backup, date)
+	}
+	if err != nil {
+		return fmt.Errorf("%s is in place, but recording its history against %s failed: %w", table, backup, err)
+	}
+	return nil
+}
+
+// recordRollbackHistory records what the rollback of table changed in the
+// history, on the day of the rollback.
+func recordRollbackHistory(db *sql.DB, table, rolledBack string) error {
+	if table != voertuigenTable {
+		return nil
+	}
+	now := time.Now()
+	date := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if err := recordReplacedTable(db, rolledBack, date); err != nil {
+		return fmt.Errorf("%s is rolled back, but recording its history against %s failed: %w", table, rolledBack, err)
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestBackupTables(t *testing.T) {
+	names := []string{
+		"voertuigen_backup_20260101120000",
+		"voertuigen_backup_20260101120001",
+		"voertuigen_backup_20260101120002",
+		"voertuigen_backup_20251231235959999",
+		"voertuigen_backup_old",
+		"voertuigen_backupx20260101120000",
+		"voertuigen_brandstof_backup_20260101120000",
+	}
+	want := []string{
+		"voertuigen_backup_20260101120002",
+		"voertuigen_backup_20260101120001",
+		"voertuigen_backup_20260101120000",
+		"voertuigen_backup_20251231235959999",
+	}
+	if got := backupTables("voertuigen", names); !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestTableTimestamp(t *testing.T) {
+	first := tableTimestamp()
+	for tableTimestamp() == first {
+	}
+	if second := tableTimestamp(); len(first) != 17 || second <= first {
+		t.Errorf("got %s after %s", second, first)
+	}
+}